@@ -0,0 +1,23 @@
+module github.com/lrills/helm-unittest
+
+go 1.13
+
+require (
+	github.com/Masterminds/semver v1.4.2 // indirect
+	github.com/Masterminds/sprig v2.19.0+incompatible // indirect
+	github.com/bradleyjkemp/cupaloy v2.3.0+incompatible
+	github.com/fatih/color v1.7.0
+	github.com/golang/protobuf v1.3.4
+	github.com/google/cel-go v0.5.1
+	github.com/mitchellh/mapstructure v1.1.2
+	github.com/open-policy-agent/opa v0.21.1
+	github.com/pmezard/go-difflib v1.0.0
+	github.com/spf13/cobra v1.0.0
+	github.com/stretchr/testify v1.5.1
+	github.com/xeipuuv/gojsonschema v1.2.0
+	github.com/yashtewari/glob-intersection v0.2.0 // indirect
+	gopkg.in/yaml.v2 v2.2.8
+	helm.sh/helm/v3 v3.2.4
+	k8s.io/client-go v0.18.0
+	k8s.io/helm v2.13.1+incompatible
+)
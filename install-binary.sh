@@ -13,6 +13,21 @@ downloadFile() {
   curl -L "$DOWNLOAD_URL" -o "$PLUGIN_TMP_FILE"
 }
 
+# downloadSchemas downloads and installs the kubernetes json schemas for validating manifests offline,
+# skipped if HELM_UNITTEST_SKIP_SCHEMAS is set
+downloadSchemas() {
+  if [ -n "$HELM_UNITTEST_SKIP_SCHEMAS" ]; then
+    echo "Skipped installing the kubernetes json schemas"
+    return
+  fi
+  SCHEMAS_URL="https://github.com/rancher/helm-unittest/releases/download/$CATTLE_HELM_UNITTEST_VERSION/$PROJECT_NAME-schemas.tgz"
+  SCHEMAS_TMP_FILE="/tmp/$PROJECT_NAME-schemas.tgz"
+  echo "Downloading $SCHEMAS_URL"
+  curl -L "$SCHEMAS_URL" -o "$SCHEMAS_TMP_FILE"
+  rm -rf "$HELM_PLUGIN_DIR/schemas"
+  tar xf "$SCHEMAS_TMP_FILE" -C "$HELM_PLUGIN_DIR"
+}
+
 # installFile verifies the SHA256 for the file, then unpacks and
 # installs it.
 installFile() {
@@ -51,4 +66,5 @@ trap "fail_trap" EXIT
 set -e
 downloadFile
 installFile
+downloadSchemas
 testVersion
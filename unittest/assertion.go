@@ -2,7 +2,6 @@ package unittest
 
 import (
 	"fmt"
-	"reflect"
 
 	"github.com/lrills/helm-unittest/unittest/common"
 	"github.com/lrills/helm-unittest/unittest/validators"
@@ -18,6 +17,23 @@ type Assertion struct {
 	AssertType    string
 	validator     validators.Validatable
 	antonym       bool
+	// whether DocumentIndex is given, instead of default to 0
+	documentIndexGiven bool
+	// select the documents which are hooks of the event, like "pre-upgrade"
+	Hook string
+	// rego policies for matchPolicy, defined in test suite or cli
+	policies []string
+	// release and values used to render, for expression
+	release map[string]interface{}
+	values  map[string]interface{}
+	// validate manifests with kubernetes schemas, for isValidManifest
+	schemaValidator validators.SchemaValidator
+	// kube version in capabilities, for notDeprecatedAPI
+	kubeVersion string
+	// violations of values against values.schema.json, for failedValuesSchema
+	valuesSchemaViolations []string
+	// manifests rendered with the previous chart, for isUpgradeSafe
+	previousDocs map[string][]common.K8sManifest
 }
 
 // Assert validate the rendered manifests with validator
@@ -30,16 +46,28 @@ func (a *Assertion) Assert(
 	result.Not = a.Not
 
 	rendered, ok := templatesResult[a.Template]
-	if !ok {
+	if _, chartWide := a.validator.(validators.ChartWideValidatable); !ok && !chartWide {
 		result.FailInfo = []string{"Error:", a.noFileErrMessage()}
 		return result
 	}
+	if a.Hook != "" {
+		rendered = validators.DocumentsOfHook(rendered, a.Hook)
+	}
 
 	result.Passed, result.FailInfo = a.validator.Validate(&validators.ValidateContext{
-		Docs:             rendered,
-		Index:            a.DocumentIndex,
-		Negative:         a.Not != a.antonym,
-		SnapshotComparer: snapshotComparer,
+		Docs:                   rendered,
+		Index:                  a.DocumentIndex,
+		IndexGiven:             a.documentIndexGiven,
+		Negative:               a.Not != a.antonym,
+		Policies:               a.policies,
+		Release:                a.release,
+		Values:                 a.values,
+		SchemaValidator:        a.schemaValidator,
+		KubeVersion:            a.kubeVersion,
+		AllDocs:                templatesResult,
+		ValuesSchemaViolations: a.valuesSchemaViolations,
+		PreviousDocs:           a.previousDocs,
+		SnapshotComparer:       snapshotComparer,
 	})
 	return result
 }
@@ -63,6 +91,7 @@ func (a *Assertion) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 	if documentIndex, ok := assertDef["documentIndex"].(int); ok {
 		a.DocumentIndex = documentIndex
+		a.documentIndexGiven = true
 	}
 	if not, ok := assertDef["not"].(bool); ok {
 		a.Not = not
@@ -70,6 +99,9 @@ func (a *Assertion) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if template, ok := assertDef["template"].(string); ok {
 		a.Template = template
 	}
+	if hook, ok := assertDef["hook"].(string); ok {
+		a.Hook = hook
+	}
 
 	if err := a.constructValidator(assertDef); err != nil {
 		return err
@@ -77,7 +109,7 @@ func (a *Assertion) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 	if a.validator == nil {
 		for key := range assertDef {
-			if key != "file" && key != "documentIndex" && key != "not" {
+			if key != "file" && key != "documentIndex" && key != "not" && key != "hook" {
 				return fmt.Errorf("Assertion type `%s` is invalid", key)
 			}
 		}
@@ -98,37 +130,74 @@ func (a *Assertion) constructValidator(assertDef map[string]interface{}) error {
 				)
 			}
 
-			validator := reflect.New(correspondDef.validatorType).Interface()
+			validator := correspondDef.factory()
 			if err := mapstructure.Decode(params, validator); err != nil {
 				return err
 			}
 
 			a.AssertType = assertName
-			a.validator = validator.(validators.Validatable)
+			a.validator = validator
 			a.antonym = correspondDef.antonym
 		}
 	}
 	return nil
 }
 
+// ValidatorFactory creates a new validator for the assertion type registered with.
+// It must return a pointer, so that the parameters of assertion can be decoded into
+type ValidatorFactory func() validators.Validatable
+
 type assertTypeDef struct {
-	validatorType reflect.Type
-	antonym       bool
+	factory ValidatorFactory
+	antonym bool
+}
+
+var assertTypeMapping = map[string]assertTypeDef{}
+
+// RegisterValidator registers the assertion type `name` with the validator created by factory,
+// and the assertion type `antonym` asserting contrarily if it's not empty.
+// It panics if the name or antonym is empty or already registered
+func RegisterValidator(name string, factory ValidatorFactory, antonym string) {
+	if factory == nil {
+		panic("unittest: RegisterValidator factory is nil")
+	}
+	registerAssertType(name, assertTypeDef{factory, false})
+	if antonym != "" {
+		registerAssertType(antonym, assertTypeDef{factory, true})
+	}
+}
+
+func registerAssertType(name string, def assertTypeDef) {
+	if name == "" {
+		panic("unittest: RegisterValidator assertion type is empty")
+	}
+	if _, existed := assertTypeMapping[name]; existed {
+		panic(fmt.Sprintf("unittest: RegisterValidator called twice for assertion type `%s`", name))
+	}
+	assertTypeMapping[name] = def
 }
 
-var assertTypeMapping = map[string]assertTypeDef{
-	"matchSnapshot": {reflect.TypeOf(validators.MatchSnapshotValidator{}), false},
-	"equal":         {reflect.TypeOf(validators.EqualValidator{}), false},
-	"notEqual":      {reflect.TypeOf(validators.EqualValidator{}), true},
-	"matchRegex":    {reflect.TypeOf(validators.MatchRegexValidator{}), false},
-	"notMatchRegex": {reflect.TypeOf(validators.MatchRegexValidator{}), true},
-	"contains":      {reflect.TypeOf(validators.ContainsValidator{}), false},
-	"notContains":   {reflect.TypeOf(validators.ContainsValidator{}), true},
-	"isNull":        {reflect.TypeOf(validators.IsNullValidator{}), false},
-	"isNotNull":     {reflect.TypeOf(validators.IsNullValidator{}), true},
-	"isEmpty":       {reflect.TypeOf(validators.IsEmptyValidator{}), false},
-	"isNotEmpty":    {reflect.TypeOf(validators.IsEmptyValidator{}), true},
-	"isKind":        {reflect.TypeOf(validators.IsKindValidator{}), false},
-	"isAPIVersion":  {reflect.TypeOf(validators.IsAPIVersionValidator{}), false},
-	"hasDocuments":  {reflect.TypeOf(validators.HasDocumentsValidator{}), false},
+func init() {
+	RegisterValidator("matchSnapshot", func() validators.Validatable { return &validators.MatchSnapshotValidator{} }, "")
+	RegisterValidator("equal", func() validators.Validatable { return &validators.EqualValidator{} }, "notEqual")
+	RegisterValidator("matchRegex", func() validators.Validatable { return &validators.MatchRegexValidator{} }, "notMatchRegex")
+	RegisterValidator("contains", func() validators.Validatable { return &validators.ContainsValidator{} }, "notContains")
+	RegisterValidator("isNull", func() validators.Validatable { return &validators.IsNullValidator{} }, "isNotNull")
+	RegisterValidator("isEmpty", func() validators.Validatable { return &validators.IsEmptyValidator{} }, "isNotEmpty")
+	RegisterValidator("isKind", func() validators.Validatable { return &validators.IsKindValidator{} }, "")
+	RegisterValidator("isAPIVersion", func() validators.Validatable { return &validators.IsAPIVersionValidator{} }, "")
+	RegisterValidator("hasDocuments", func() validators.Validatable { return &validators.HasDocumentsValidator{} }, "")
+	RegisterValidator("custom", func() validators.Validatable { return &validators.CustomValidator{} }, "")
+	RegisterValidator("matchPolicy", func() validators.Validatable { return &validators.MatchPolicyValidator{} }, "")
+	RegisterValidator("expression", func() validators.Validatable { return &validators.ExpressionValidator{} }, "")
+	RegisterValidator("isValidManifest", func() validators.Validatable { return &validators.IsValidManifestValidator{} }, "")
+	RegisterValidator("notDeprecatedAPI", func() validators.Validatable { return &validators.NotDeprecatedAPIValidator{} }, "")
+	RegisterValidator("serviceSelectsPods", func() validators.Validatable { return &validators.ServiceSelectsPodsValidator{} }, "")
+	RegisterValidator("referencesExist", func() validators.Validatable { return &validators.ReferencesExistValidator{} }, "")
+	RegisterValidator("followsBestPractices", func() validators.Validatable { return &validators.BestPracticesValidator{} }, "")
+	RegisterValidator("meetsPodSecurityStandard", func() validators.Validatable { return &validators.MeetsPodSecurityStandardValidator{} }, "")
+	RegisterValidator("failedValuesSchema", func() validators.Validatable { return &validators.FailedValuesSchemaValidator{} }, "")
+	RegisterValidator("isHook", func() validators.Validatable { return &validators.IsHookValidator{} }, "")
+	RegisterValidator("runsBeforeResources", func() validators.Validatable { return &validators.RunsBeforeResourcesValidator{} }, "")
+	RegisterValidator("isUpgradeSafe", func() validators.Validatable { return &validators.IsUpgradeSafeValidator{} }, "")
 }
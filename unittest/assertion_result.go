@@ -10,10 +10,12 @@ type AssertionResult struct {
 	AssertType string
 	Not        bool
 	CustomInfo string
+	// the fail info is reported as warning, and the assertion is passed
+	Warning bool
 }
 
 func (ar AssertionResult) print(printer *Printer, verbosity int) {
-	if ar.Passed {
+	if ar.Passed && !ar.Warning {
 		return
 	}
 	var title string
@@ -26,7 +28,11 @@ func (ar AssertionResult) print(printer *Printer, verbosity int) {
 		}
 		title = fmt.Sprintf("- asserts[%d]%s `%s` fail", ar.Index, notAnnotation, ar.AssertType)
 	}
-	printer.println(printer.danger(title+"\n"), 2)
+	if ar.Warning {
+		printer.println(printer.warning(title+"\n"), 2)
+	} else {
+		printer.println(printer.danger(title+"\n"), 2)
+	}
 	for _, infoLine := range ar.FailInfo {
 		printer.println(infoLine, 3)
 	}
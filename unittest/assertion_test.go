@@ -6,6 +6,7 @@ import (
 	. "github.com/lrills/helm-unittest/unittest"
 	"github.com/lrills/helm-unittest/unittest/common"
 	"github.com/lrills/helm-unittest/unittest/snapshot"
+	"github.com/lrills/helm-unittest/unittest/validators"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
 )
@@ -239,3 +240,58 @@ func TestAssertionAssertWhenTemplateNotSpecifiedAndNoDefault(t *testing.T) {
 		CustomInfo: "",
 	}, result)
 }
+
+type fakeValidator struct {
+	Expected string
+}
+
+func (v *fakeValidator) Validate(context *validators.ValidateContext) (bool, []string) {
+	manifest, err := context.GetManifest()
+	if err != nil {
+		return false, []string{err.Error()}
+	}
+	if (manifest["kind"] == v.Expected) != context.Negative {
+		return true, []string{}
+	}
+	return false, []string{"fake fail"}
+}
+
+// registered once, as the assertion types can't be registered twice when tests run with -count
+func init() {
+	RegisterValidator("isFake", func() validators.Validatable { return &fakeValidator{} }, "isNotFake")
+}
+
+func TestAssertionWithRegisteredValidator(t *testing.T) {
+	manifest := common.K8sManifest{"kind": "Fake"}
+	renderedMap := map[string][]common.K8sManifest{
+		"t.yaml": {manifest},
+	}
+	assertionsYAML := `
+- template: t.yaml
+  isFake:
+    expected: Fake
+- template: t.yaml
+  isNotFake:
+    expected: Fake
+`
+	assertions := make([]Assertion, 2)
+	err := yaml.Unmarshal([]byte(assertionsYAML), &assertions)
+
+	a := assert.New(t)
+	a.Nil(err)
+
+	result := assertions[0].Assert(renderedMap, fakeSnapshotComparer(true), &AssertionResult{Index: 0})
+	a.True(result.Passed)
+	a.Equal("isFake", result.AssertType)
+
+	result = assertions[1].Assert(renderedMap, fakeSnapshotComparer(true), &AssertionResult{Index: 1})
+	a.False(result.Passed)
+	a.Equal("isNotFake", result.AssertType)
+	a.Equal([]string{"fake fail"}, result.FailInfo)
+}
+
+func TestRegisterValidatorTwicePanics(t *testing.T) {
+	assert.Panics(t, func() {
+		RegisterValidator("equal", func() validators.Validatable { return &validators.EqualValidator{} }, "")
+	})
+}
@@ -0,0 +1,113 @@
+package unittest
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+
+	"github.com/golang/protobuf/ptypes/any"
+	v3chart "helm.sh/helm/v3/pkg/chart"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// ChartFile the file of chart added or replaced in test, which is read by `.Files` in templates
+type ChartFile struct {
+	// the name of file relative to the chart, like "config/app.conf"
+	Name string
+	// the content of file given inline
+	Content string
+	// the file to take the content from, relative to the suite file
+	Path string
+}
+
+type chartFileContent struct {
+	name string
+	data []byte
+}
+
+// contentsOfFiles returns the contents of files to override in order, read from path if given
+func (t *TestJob) contentsOfFiles() ([]chartFileContent, error) {
+	contents := make([]chartFileContent, 0, len(t.Files))
+	for _, file := range t.Files {
+		if file.Name == "" {
+			return nil, fmt.Errorf("name of files is required")
+		}
+		content := []byte(file.Content)
+		if file.Path != "" {
+			if file.Content != "" {
+				return nil, fmt.Errorf("only one of content and path of file %s can be given", file.Name)
+			}
+			var err error
+			if content, err = t.readFile(relativeTo(filepath.Dir(t.definitionFile), file.Path)); err != nil {
+				return nil, err
+			}
+		}
+		contents = append(contents, chartFileContent{name: path.Clean(file.Name), data: content})
+	}
+	return contents, nil
+}
+
+// overrideFilesOfChart adds or replaces the files of the chart tested in the route of test,
+// the chart given should be a copy since the files of it or its dependency are replaced
+func (t *TestJob) overrideFilesOfChart(targetChart *chart.Chart) error {
+	if len(t.Files) == 0 {
+		return nil
+	}
+	contents, err := t.contentsOfFiles()
+	if err != nil {
+		return err
+	}
+
+	testedChart, err := chartOfRoute(targetChart, t.chartRoute)
+	if err != nil {
+		return err
+	}
+
+	files := make([]*any.Any, 0, len(testedChart.Files)+len(contents))
+	replaced := make(map[string]bool, len(contents))
+	for _, content := range contents {
+		replaced[content.name] = true
+	}
+	for _, file := range testedChart.Files {
+		if !replaced[path.Clean(file.TypeUrl)] {
+			files = append(files, file)
+		}
+	}
+	for _, content := range contents {
+		files = append(files, &any.Any{TypeUrl: content.name, Value: content.data})
+	}
+	testedChart.Files = files
+	return nil
+}
+
+// overrideFilesOfHelm3Chart is overrideFilesOfChart for the charts rendered with helm 3
+func (t *TestJob) overrideFilesOfHelm3Chart(helm3Chart *v3chart.Chart) error {
+	if len(t.Files) == 0 {
+		return nil
+	}
+	contents, err := t.contentsOfFiles()
+	if err != nil {
+		return err
+	}
+
+	testedChart, err := helm3ChartOfRoute(helm3Chart, t.chartRoute)
+	if err != nil {
+		return err
+	}
+
+	files := make([]*v3chart.File, 0, len(testedChart.Files)+len(contents))
+	replaced := make(map[string]bool, len(contents))
+	for _, content := range contents {
+		replaced[content.name] = true
+	}
+	for _, file := range testedChart.Files {
+		if !replaced[path.Clean(file.Name)] {
+			files = append(files, file)
+		}
+	}
+	for _, content := range contents {
+		files = append(files, &v3chart.File{Name: content.name, Data: content.data})
+	}
+	testedChart.Files = files
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/lrills/helm-unittest/unittest/common"
 	"github.com/spf13/cobra"
 )
 
@@ -14,6 +15,28 @@ type TestConfig struct {
 	UpdateSnapshot bool
 	WithSubChart   bool
 	TestFiles      []string
+	Policies       []string
+	ValidateSchema bool
+	// directories of kubernetes schemas, besides the ones bundled
+	SchemaLocations []string
+	// files or directories of CRDs to validate custom resources
+	CRDs []string
+	// kube version to render with and check deprecated APIs against
+	KubeVersion string
+	// file of api versions dumped by `kubectl api-versions` to render with
+	APIVersionsFile string
+	// check the best practice rules in all test suites
+	BestPractices bool
+	// helm version to render charts with, auto-detected if empty
+	HelmVersion string
+	// directory to cache the snapshots of test suites packaged in chart archives
+	SnapshotDir string
+	// directory of test suites kept outside the charts
+	TestsDir string
+	// render with the random and crypto functions seeded or stubbed
+	Deterministic bool
+	// the previous version of chart to check the upgrade safety against, a path or "git:<ref>"
+	UpgradeFrom string
 }
 
 var testConfig = TestConfig{}
@@ -49,10 +72,27 @@ Or specify the suite files glob path pattern:
 
 $ helm unittest -f 'my-tests/*.yaml' my-chart
 
+Or run all the charts under a directory:
+
+$ helm unittest ./charts/...
+
 Check https://github.com/lrills/helm-unittest for more
 details about how to write tests.
 `,
 	Args: cobra.MinimumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, chartPaths []string) error {
+		if testConfig.KubeVersion != "" {
+			if _, _, err := common.ParseKubeVersion(testConfig.KubeVersion); err != nil {
+				return err
+			}
+		}
+		switch testConfig.HelmVersion {
+		case HelmVersionAuto, HelmVersion2, HelmVersion3:
+		default:
+			return fmt.Errorf("invalid helm version %s, should be 2 or 3", testConfig.HelmVersion)
+		}
+		return nil
+	},
 	Run: func(cmd *cobra.Command, chartPaths []string) {
 		var colored *bool
 		if cmd.PersistentFlags().Changed("color") {
@@ -97,4 +137,64 @@ func init() {
 		&testConfig.WithSubChart, "with-subchart", "s", true,
 		"include tests of the subcharts within `charts` folder",
 	)
+
+	cmd.PersistentFlags().StringArrayVar(
+		&testConfig.Policies, "policies", []string{},
+		"paths of rego policy files or directories used by matchPolicy assertions in all test suites",
+	)
+
+	cmd.PersistentFlags().BoolVar(
+		&testConfig.ValidateSchema, "validate-schema", false,
+		"validate all manifests rendered with the kubernetes schemas of the kube version in capabilities",
+	)
+
+	cmd.PersistentFlags().StringArrayVar(
+		&testConfig.SchemaLocations, "schema-location", []string{},
+		"directories of kubernetes schemas used besides the bundled ones, in the layout of v1.14.0-standalone-strict/deployment-apps-v1.json",
+	)
+
+	cmd.PersistentFlags().StringArrayVar(
+		&testConfig.CRDs, "crds", []string{},
+		"files or directories of CRDs used to validate custom resources, besides the ones in crds directory of charts or rendered",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&testConfig.KubeVersion, "kube-version", "",
+		"kube version like 1.16 or v1.27.3 to render with if not set in capabilities of test or suite, and check all manifests rendered not using APIs deprecated or removed in it",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&testConfig.APIVersionsFile, "api-versions-file", "",
+		"file of api versions dumped by kubectl api-versions, one in each line, to render with if not set in capabilities of test or suite",
+	)
+
+	cmd.PersistentFlags().BoolVar(
+		&testConfig.BestPractices, "best-practices", false,
+		"check the best practice rules on all manifests rendered in each test, unless disabled in bestPractices of test suite",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&testConfig.HelmVersion, "helm-version", HelmVersionAuto,
+		"helm version to render charts with, 2 or 3. Default to 3 for charts of apiVersion v2 in Chart.yaml, otherwise 2",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&testConfig.SnapshotDir, "snapshot-dir", "",
+		"directory to cache the snapshots of test suites packaged in chart archives. Default to __snapshot__ in the chart, or besides the chart if it's packaged",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&testConfig.TestsDir, "tests-dir", "",
+		"directory of test suites kept outside the charts, the suites are matched with the file name of --file patterns and run against the chart given in chart of the suite",
+	)
+
+	cmd.PersistentFlags().BoolVar(
+		&testConfig.Deterministic, "deterministic", false,
+		"render with the release time fixed, the random functions like randAlphaNum and uuidv4 seeded, and the crypto functions like genCA stubbed, so the output is the same every run. Skipped for charts rendered with helm 3",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&testConfig.UpgradeFrom, "upgrade-from", "",
+		"previous version of chart to check the immutable fields not changed in each test, a chart directory or archive, or git:REF for the chart at the ref of its git repository",
+	)
 }
@@ -0,0 +1,34 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var kubeVersionPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ParseKubeVersion parse the major and minor of kube version like "1.14", "1.14+" or "v1.14.3"
+func ParseKubeVersion(version string) (int, int, error) {
+	matched := kubeVersionPattern.FindStringSubmatch(version)
+	if matched == nil {
+		return 0, 0, fmt.Errorf("invalid kube version %s", version)
+	}
+	major, _ := strconv.Atoi(matched[1])
+	minor, _ := strconv.Atoi(matched[2])
+	return major, minor, nil
+}
+
+// GitVersionOfKubeVersion returns the git version like "v1.14.3" of kube version like "1.14" or "v1.14.3",
+// the patch version is default to 0 if not given
+func GitVersionOfKubeVersion(version string) (string, error) {
+	matched := kubeVersionPattern.FindStringSubmatch(version)
+	if matched == nil {
+		return "", fmt.Errorf("invalid kube version %s", version)
+	}
+	patch := matched[3]
+	if patch == "" {
+		patch = "0"
+	}
+	return fmt.Sprintf("v%s.%s.%s", matched[1], matched[2], patch), nil
+}
@@ -1,6 +1,11 @@
 package common
 
-import yaml "gopkg.in/yaml.v2"
+import (
+	"fmt"
+	"reflect"
+
+	yaml "gopkg.in/yaml.v2"
+)
 
 // TrustedMarshalYAML marshal yaml without error returned, if an error happens it panics
 func TrustedMarshalYAML(d interface{}) string {
@@ -10,3 +15,55 @@ func TrustedMarshalYAML(d interface{}) string {
 	}
 	return string(s)
 }
+
+// ConvertToJSONCompatible convert the map[interface{}]interface{} unmarshaled by yaml
+// into map[string]interface{} recursively, so the data can be marshaled as json
+func ConvertToJSONCompatible(d interface{}) interface{} {
+	switch v := d.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			converted[fmt.Sprintf("%v", key)] = ConvertToJSONCompatible(value)
+		}
+		return converted
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			converted[key] = ConvertToJSONCompatible(value)
+		}
+		return converted
+	case K8sManifest:
+		converted := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			converted[key] = ConvertToJSONCompatible(value)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for idx, value := range v {
+			converted[idx] = ConvertToJSONCompatible(value)
+		}
+		return converted
+	default:
+		// named map and slice types, like chartutil.Values
+		value := reflect.ValueOf(d)
+		switch value.Kind() {
+		case reflect.Map:
+			converted := make(map[string]interface{}, value.Len())
+			for _, key := range value.MapKeys() {
+				converted[fmt.Sprintf("%v", key.Interface())] = ConvertToJSONCompatible(value.MapIndex(key).Interface())
+			}
+			return converted
+		case reflect.Slice:
+			if value.Type().Elem().Kind() == reflect.Uint8 {
+				return d
+			}
+			converted := make([]interface{}, value.Len())
+			for idx := 0; idx < value.Len(); idx++ {
+				converted[idx] = ConvertToJSONCompatible(value.Index(idx).Interface())
+			}
+			return converted
+		}
+		return d
+	}
+}
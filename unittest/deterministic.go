@@ -0,0 +1,105 @@
+package unittest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"text/template"
+	"time"
+)
+
+// the release time rendered with if deterministic and not frozen in test
+var deterministicReleaseTime = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// the seed of random functions, the same values are generated in the same order every render
+const deterministicSeed = 0
+
+const (
+	alphaChars   = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	numericChars = "0123456789"
+)
+
+// stubCertificate has the same fields as the certificate generated by sprig
+type stubCertificate struct {
+	Cert string
+	Key  string
+}
+
+// nowFuncMap returns the `now` function returning the release time frozen
+func nowFuncMap(releaseTime time.Time) template.FuncMap {
+	return template.FuncMap{
+		"now": func() time.Time { return releaseTime },
+	}
+}
+
+// deterministicFuncMap returns the sprig random functions seeded, and the crypto functions stubbed
+// to return placeholders derived from their arguments, so the output is the same every render
+func deterministicFuncMap() template.FuncMap {
+	random := rand.New(rand.NewSource(deterministicSeed))
+	randString := func(chars string) func(int) string {
+		return func(count int) string {
+			bytes := make([]byte, count)
+			for idx := range bytes {
+				bytes[idx] = chars[random.Intn(len(chars))]
+			}
+			return string(bytes)
+		}
+	}
+	asciiChars := make([]byte, 0, 95)
+	for char := byte(' '); char <= '~'; char++ {
+		asciiChars = append(asciiChars, char)
+	}
+
+	return template.FuncMap{
+		"randAlphaNum": randString(alphaChars + numericChars),
+		"randAlpha":    randString(alphaChars),
+		"randNumeric":  randString(numericChars),
+		"randAscii":    randString(string(asciiChars)),
+		"randBytes": func(count int) (string, error) {
+			bytes := make([]byte, count)
+			random.Read(bytes)
+			return base64.StdEncoding.EncodeToString(bytes), nil
+		},
+		"uuidv4": func() string {
+			bytes := make([]byte, 16)
+			random.Read(bytes)
+			// version 4 and variant bits as RFC 4122
+			bytes[6] = (bytes[6] & 0x0f) | 0x40
+			bytes[8] = (bytes[8] & 0x3f) | 0x80
+			return fmt.Sprintf("%x-%x-%x-%x-%x", bytes[0:4], bytes[4:6], bytes[6:8], bytes[8:10], bytes[10:])
+		},
+		"genPrivateKey": func(typ string) string {
+			return stubPEM("PRIVATE KEY", "genPrivateKey", typ)
+		},
+		"genCA": func(cn string, daysValid int) (stubCertificate, error) {
+			return stubCertificateOf("genCA", cn, daysValid), nil
+		},
+		"genSelfSignedCert": func(
+			cn string, ips []interface{}, alternateDNS []interface{}, daysValid int,
+		) (stubCertificate, error) {
+			return stubCertificateOf("genSelfSignedCert", cn, ips, alternateDNS, daysValid), nil
+		},
+		"genSignedCert": func(
+			cn string, ips []interface{}, alternateDNS []interface{}, daysValid int, ca interface{},
+		) (stubCertificate, error) {
+			return stubCertificateOf("genSignedCert", cn, ips, alternateDNS, daysValid, ca), nil
+		},
+	}
+}
+
+func stubCertificateOf(function string, args ...interface{}) stubCertificate {
+	return stubCertificate{
+		Cert: stubPEM("CERTIFICATE", function, args...),
+		Key:  stubPEM("RSA PRIVATE KEY", function, args...),
+	}
+}
+
+// stubPEM returns a PEM block of typ, whose content is the digest of function and args
+func stubPEM(typ string, function string, args ...interface{}) string {
+	digest := sha256.Sum256([]byte(fmt.Sprintf("%s %s %v", typ, function, args)))
+	return fmt.Sprintf(
+		"-----BEGIN %s-----\n%s\n-----END %s-----\n",
+		typ, base64.StdEncoding.EncodeToString(digest[:]), typ,
+	)
+}
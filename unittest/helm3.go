@@ -0,0 +1,231 @@
+package unittest
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	"github.com/lrills/helm-unittest/unittest/schema"
+	v3chart "helm.sh/helm/v3/pkg/chart"
+	v3chartutil "helm.sh/helm/v3/pkg/chartutil"
+	v3engine "helm.sh/helm/v3/pkg/engine"
+	"k8s.io/client-go/rest"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+const (
+	// HelmVersionAuto detect the helm version to render with from the apiVersion in Chart.yaml
+	HelmVersionAuto = ""
+	// HelmVersion2 render charts like helm 2
+	HelmVersion2 = "2"
+	// HelmVersion3 render charts like helm 3
+	HelmVersion3 = "3"
+)
+
+// usesHelm3 whether the chart should be rendered with helm 3 of helmVersion,
+// charts of apiVersion v2 are only supported by helm 3
+func usesHelm3(helmVersion string, targetChart *chart.Chart) bool {
+	switch helmVersion {
+	case HelmVersion3:
+		return true
+	case HelmVersion2:
+		return false
+	}
+	return targetChart.Metadata.ApiVersion == v3chart.APIVersionV2
+}
+
+// prepareHelm3Chart returns a copy of the helm 3 chart with the same templates kept in the prepared chart,
+// and the named templates overridden the same
+func prepareHelm3Chart(helm3Chart *v3chart.Chart, preparedChart *chart.Chart) *v3chart.Chart {
+	kept := make(map[string]bool, len(preparedChart.Templates))
+	for _, template := range preparedChart.Templates {
+		kept[template.Name] = true
+	}
+
+	copiedChart := copyHelm3Chart(helm3Chart)
+	templates := make([]*v3chart.File, 0, len(kept))
+	for _, template := range helm3Chart.Templates {
+		if kept[template.Name] {
+			templates = append(templates, template)
+		}
+	}
+	copiedChart.Templates = templates
+	for _, template := range preparedChart.Templates {
+		if template.Name == templateOverridesFile {
+			copiedChart.Templates = withHelm3TemplateOverrides(copiedChart.Templates, template)
+		}
+	}
+	return copiedChart
+}
+
+// copyHelm3Chart copy the chart and its dependencies, so that the values and dependencies
+// modified by chartutil.ProcessDependencies don't affect the other tests
+func copyHelm3Chart(helm3Chart *v3chart.Chart) *v3chart.Chart {
+	copiedChart := *helm3Chart
+
+	metadata := *helm3Chart.Metadata
+	metadata.Dependencies = make([]*v3chart.Dependency, len(helm3Chart.Metadata.Dependencies))
+	for idx, dependency := range helm3Chart.Metadata.Dependencies {
+		copiedDependency := *dependency
+		metadata.Dependencies[idx] = &copiedDependency
+	}
+	copiedChart.Metadata = &metadata
+
+	dependencies := make([]*v3chart.Chart, 0, len(helm3Chart.Dependencies()))
+	for _, dependency := range helm3Chart.Dependencies() {
+		dependencies = append(dependencies, copyHelm3Chart(dependency))
+	}
+	copiedChart.SetDependencies(dependencies...)
+	return &copiedChart
+}
+
+// renderHelm3Chart renders the chart with user values like `helm template` of helm 3,
+// the dependencies are enabled and imported before rendering
+func (t *TestJob) renderHelm3Chart(userValues []byte) (map[string]string, chartutil.Values, error) {
+	// the template functions of helm 3 engine can't be overridden to freeze `now`,
+	// --deterministic is skipped for the whole chart by the runner instead
+	if t.Release.Time != "" {
+		return nil, nil, fmt.Errorf(
+			"release.time is not supported for charts rendered with helm 3, " +
+				"remove it or render with --helm-version 2",
+		)
+	}
+
+	helm3Chart := copyHelm3Chart(t.helm3Chart)
+	if err := t.overrideFilesOfHelm3Chart(helm3Chart); err != nil {
+		return nil, nil, err
+	}
+	if overrides := t.templateOverrides(); overrides != nil {
+		helm3Chart.Templates = withHelm3TemplateOverrides(helm3Chart.Templates, overrides)
+	}
+	if err := t.injectHelm3NamedTemplate(helm3Chart); err != nil {
+		return nil, nil, err
+	}
+
+	vals, err := v3chartutil.ReadValues(userValues)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := v3chartutil.ProcessDependencies(helm3Chart, vals); err != nil {
+		return nil, nil, err
+	}
+
+	caps := t.helm3CapabilityOption()
+	if helm3Chart.Metadata.KubeVersion != "" &&
+		!v3chartutil.IsCompatibleRange(helm3Chart.Metadata.KubeVersion, caps.KubeVersion.String()) {
+		return nil, nil, fmt.Errorf(
+			"chart requires kubeVersion: %s which is incompatible with Kubernetes %s",
+			helm3Chart.Metadata.KubeVersion, caps.KubeVersion.String(),
+		)
+	}
+
+	// validate the values schema before helm does, so the violations could be asserted
+	coalescedValues, err := v3chartutil.CoalesceValues(helm3Chart, vals)
+	if err != nil {
+		return nil, nil, err
+	}
+	values := chartutil.Values(coalescedValues)
+	violations, err := helm3ValuesSchemaViolations(helm3Chart, values)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(violations) > 0 {
+		return nil, chartutil.Values{"Values": values}, &valuesSchemaError{violations}
+	}
+
+	renderValues, err := v3chartutil.ToRenderValues(helm3Chart, vals, t.helm3ReleaseOption(), caps)
+	if err != nil {
+		return nil, nil, err
+	}
+	cluster, err := t.fakeCluster()
+	if err != nil {
+		return nil, nil, err
+	}
+	var config *rest.Config
+	if cluster != nil {
+		// lookup the objects declared from the fake api server
+		server := cluster.serve()
+		defer server.Close()
+		config = &rest.Config{Host: server.URL}
+	}
+	outputOfFiles, err := v3engine.RenderWithClient(helm3Chart, renderValues, config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return outputOfFiles, chartutil.Values{"Values": values}, nil
+}
+
+// get v3chartutil.ReleaseOptions ready for render
+func (t *TestJob) helm3ReleaseOption() v3chartutil.ReleaseOptions {
+	options := t.releaseOption()
+	return v3chartutil.ReleaseOptions{
+		Name:      options.Name,
+		Namespace: options.Namespace,
+		Revision:  options.Revision,
+		IsInstall: options.IsInstall,
+		IsUpgrade: options.IsUpgrade,
+	}
+}
+
+// get v3chartutil.Capabilities ready for render like the helm 2 one,
+// the kube version defaults to the one of helm 3 if not set
+func (t *TestJob) helm3CapabilityOption() *v3chartutil.Capabilities {
+	caps := *v3chartutil.DefaultCapabilities
+	if major, minor, err := common.ParseKubeVersion(t.resolvedKubeVersion); err == nil {
+		caps.KubeVersion = v3chartutil.KubeVersion{
+			Version: t.resolvedKubeVersion,
+			Major:   strconv.Itoa(major),
+			Minor:   strconv.Itoa(minor),
+		}
+	}
+	if t.Capabilities.KubeVersionMajor != "" || t.Capabilities.KubeVersionMinor != "" {
+		if t.Capabilities.KubeVersionMajor != "" {
+			caps.KubeVersion.Major = t.Capabilities.KubeVersionMajor
+		}
+		if t.Capabilities.KubeVersionMinor != "" {
+			caps.KubeVersion.Minor = t.Capabilities.KubeVersionMinor
+		}
+		caps.KubeVersion.Version = fmt.Sprintf("v%s.%s.0", caps.KubeVersion.Major, caps.KubeVersion.Minor)
+	}
+	if len(t.resolvedAPIVersions) > 0 {
+		caps.APIVersions = append(v3chartutil.VersionSet{"v1"}, t.resolvedAPIVersions...)
+	}
+	return &caps
+}
+
+// helm3ValuesSchemaViolations validates the values of chart and its enabled dependencies
+// with their values.schema.json, the violations are prefixed with the chart name
+func helm3ValuesSchemaViolations(helm3Chart *v3chart.Chart, values map[string]interface{}) ([]string, error) {
+	violations, err := valuesSchemaViolations(helm3Chart.Name(), helm3Chart.Schema, values)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dependency := range helm3Chart.Dependencies() {
+		dependencyViolations, err := helm3ValuesSchemaViolations(dependency, valuesOfDependency(values, dependency.Name()))
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, dependencyViolations...)
+	}
+	return violations, nil
+}
+
+// valuesSchemaViolations validates the values of chart name with schemaContent, if it's not empty
+func valuesSchemaViolations(name string, schemaContent []byte, values map[string]interface{}) ([]string, error) {
+	violations := make([]string, 0)
+	if len(schemaContent) == 0 {
+		return violations, nil
+	}
+
+	schemaViolations, err := schema.ValidateValues(schemaContent, values)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s of chart %s: %s", valuesSchemaFile, name, err)
+	}
+	for _, violation := range schemaViolations {
+		violations = append(violations, fmt.Sprintf("%s%s: %s", name, violation.Pointer, violation.Message))
+	}
+	return violations, nil
+}
@@ -0,0 +1,269 @@
+package unittest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// KubernetesProvider declares the objects existing in the fake cluster,
+// which are returned by `lookup` in templates
+type KubernetesProvider struct {
+	// the objects declared inline
+	Objects []map[string]interface{}
+	// the files of objects, relative to the suite file
+	Files []string
+}
+
+// fakeCluster the cluster of objects declared, to lookup objects from
+type fakeCluster struct {
+	objects []map[string]interface{}
+	// the kinds of objects by their resource names, like "secrets" of "Secret"
+	kinds map[string]string
+	// whether the kind is namespaced, if any object of it has namespace
+	namespaced map[string]bool
+}
+
+func newFakeCluster(objects []map[string]interface{}) *fakeCluster {
+	cluster := &fakeCluster{
+		objects:    objects,
+		kinds:      make(map[string]string),
+		namespaced: make(map[string]bool),
+	}
+	for _, object := range objects {
+		kind := stringOfObject(object, "kind")
+		cluster.kinds[resourceOfKind(kind)] = kind
+		if stringOfObject(object, "metadata", "namespace") != "" {
+			cluster.namespaced[kind] = true
+		}
+	}
+	return cluster
+}
+
+// lookup like `lookup` of helm, returns the object if name given, otherwise the list of objects.
+// An empty object is returned if not found
+func (c *fakeCluster) lookup(apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+	matched := make([]interface{}, 0)
+	for _, object := range c.objects {
+		if stringOfObject(object, "apiVersion") != apiVersion ||
+			stringOfObject(object, "kind") != kind ||
+			(name != "" && stringOfObject(object, "metadata", "name") != name) ||
+			(namespace != "" && c.namespaced[kind] && stringOfObject(object, "metadata", "namespace") != namespace) {
+			continue
+		}
+		if name != "" {
+			return object, nil
+		}
+		matched = append(matched, object)
+	}
+
+	if name != "" {
+		return map[string]interface{}{}, nil
+	}
+	return map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind + "List",
+		"metadata":   map[string]interface{}{},
+		"items":      matched,
+	}, nil
+}
+
+// serve the objects like kubernetes api server, so that the `lookup` of helm 3 can request
+func (c *fakeCluster) serve() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		var groupVersion string
+		var segments []string
+		path := strings.Trim(request.URL.Path, "/")
+		switch {
+		case strings.HasPrefix(path, "api/"):
+			segments = strings.Split(strings.TrimPrefix(path, "api/"), "/")
+			groupVersion, segments = segments[0], segments[1:]
+		case strings.HasPrefix(path, "apis/") && len(strings.Split(path, "/")) >= 3:
+			segments = strings.Split(strings.TrimPrefix(path, "apis/"), "/")
+			groupVersion, segments = segments[0]+"/"+segments[1], segments[2:]
+		default:
+			writeNotFound(writer)
+			return
+		}
+
+		if len(segments) == 0 {
+			if !c.servesGroupVersion(groupVersion, strings.HasPrefix(path, "apis/")) {
+				writeNotFound(writer)
+				return
+			}
+			writeJSON(writer, c.resourceList(groupVersion))
+			return
+		}
+
+		var namespace, resource, name string
+		switch {
+		case segments[0] == "namespaces" && len(segments) >= 3:
+			namespace, resource = segments[1], segments[2]
+			if len(segments) > 3 {
+				name = segments[3]
+			}
+		case len(segments) <= 2:
+			resource = segments[0]
+			if len(segments) > 1 {
+				name = segments[1]
+			}
+		}
+
+		kind, ok := c.kinds[resource]
+		if !ok {
+			writeNotFound(writer)
+			return
+		}
+		object, _ := c.lookup(groupVersion, kind, namespace, name)
+		if len(object) == 0 {
+			writeNotFound(writer)
+			return
+		}
+		writeJSON(writer, object)
+	}))
+}
+
+// servesGroupVersion whether the discovery of groupVersion is served. The kind not found in discovery
+// is requested without group version like "/api/NAME", so "/api/GROUP_VERSION" is served only for the
+// core "v1" and the group versions of objects declared, not to be taken as the list of anything missing
+func (c *fakeCluster) servesGroupVersion(groupVersion string, isGroup bool) bool {
+	if isGroup || groupVersion == "v1" {
+		return true
+	}
+	for _, object := range c.objects {
+		if stringOfObject(object, "apiVersion") == groupVersion {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceList returns the APIResourceList of groupVersion for discovery
+func (c *fakeCluster) resourceList(groupVersion string) map[string]interface{} {
+	resources := make([]interface{}, 0)
+	listed := make(map[string]bool)
+	for _, object := range c.objects {
+		kind := stringOfObject(object, "kind")
+		if stringOfObject(object, "apiVersion") != groupVersion || listed[kind] {
+			continue
+		}
+		listed[kind] = true
+		resources = append(resources, map[string]interface{}{
+			"name":         resourceOfKind(kind),
+			"singularName": strings.ToLower(kind),
+			"namespaced":   c.namespaced[kind],
+			"kind":         kind,
+			"verbs":        []string{"get", "list"},
+		})
+	}
+	return map[string]interface{}{
+		"kind":         "APIResourceList",
+		"apiVersion":   "v1",
+		"groupVersion": groupVersion,
+		"resources":    resources,
+	}
+}
+
+func writeJSON(writer http.ResponseWriter, content interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(content)
+}
+
+func writeNotFound(writer http.ResponseWriter) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(writer).Encode(map[string]interface{}{
+		"kind":       "Status",
+		"apiVersion": "v1",
+		"status":     "Failure",
+		"reason":     "NotFound",
+		"code":       http.StatusNotFound,
+	})
+}
+
+// resourceOfKind returns the resource name of kind in the fake cluster
+func resourceOfKind(kind string) string {
+	return strings.ToLower(kind) + "s"
+}
+
+func stringOfObject(object map[string]interface{}, fields ...string) string {
+	var value interface{} = object
+	for _, field := range fields {
+		mapValue, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = mapValue[field]
+	}
+	str, _ := value.(string)
+	return str
+}
+
+// fakeCluster returns the cluster of objects declared in the test and suite, nil if none declared
+func (t *TestJob) fakeCluster() (*fakeCluster, error) {
+	providers := make([]*KubernetesProvider, 0, 2)
+	for _, provider := range []*KubernetesProvider{t.KubernetesProvider, t.suiteKubernetesProvider} {
+		if provider != nil {
+			providers = append(providers, provider)
+		}
+	}
+	if len(providers) == 0 {
+		return nil, nil
+	}
+
+	objects := make([]map[string]interface{}, 0)
+	for _, provider := range providers {
+		for _, object := range provider.Objects {
+			objects = append(objects, common.ConvertToJSONCompatible(object).(map[string]interface{}))
+		}
+		for _, file := range provider.Files {
+			fileObjects, err := t.readKubernetesObjects(file)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, fileObjects...)
+		}
+	}
+
+	for _, object := range objects {
+		if stringOfObject(object, "apiVersion") == "" || stringOfObject(object, "kind") == "" ||
+			stringOfObject(object, "metadata", "name") == "" {
+			return nil, fmt.Errorf("apiVersion, kind and metadata.name of kubernetesProvider objects are required")
+		}
+	}
+	return newFakeCluster(objects), nil
+}
+
+// readKubernetesObjects reads the objects in file relative to the suite file, multiple documents supported
+func (t *TestJob) readKubernetesObjects(file string) ([]map[string]interface{}, error) {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(filepath.Dir(t.definitionFile), file)
+	}
+	content, err := t.readFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]map[string]interface{}, 0)
+	decoder := yaml.NewDecoder(strings.NewReader(string(content)))
+	for {
+		object := make(map[interface{}]interface{})
+		if err := decoder.Decode(object); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse %s: %s", file, err)
+		}
+		if len(object) > 0 {
+			objects = append(objects, common.ConvertToJSONCompatible(object).(map[string]interface{}))
+		}
+	}
+	return objects, nil
+}
@@ -0,0 +1,121 @@
+package unittest
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+	v3chart "helm.sh/helm/v3/pkg/chart"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// namedTemplateFile the template injected in the chart tested to include the named template of test,
+// whose output is asserted as the template of this name
+const namedTemplateFile = "unittest-named-template.yaml"
+
+// NamedTemplate the named template defined in chart to render and assert directly
+type NamedTemplate struct {
+	// the name of template like "mychart.labels"
+	Name string
+	// the context passed to the template as `.`, default to the root context with values and release of test
+	Context map[string]interface{}
+}
+
+// content returns the template including the named template with the context
+func (n *NamedTemplate) content() (string, error) {
+	if n.Name == "" {
+		return "", fmt.Errorf("name of namedTemplate is required")
+	}
+	context := "."
+	if n.Context != nil {
+		data, err := yaml.Marshal(n.Context)
+		if err != nil {
+			return "", err
+		}
+		context = fmt.Sprintf("(fromYaml %q)", string(data))
+	}
+	return fmt.Sprintf("{{ include %q %s }}", n.Name, context), nil
+}
+
+// isPartialTemplate whether the template only defines named templates, like _helpers.tpl
+func isPartialTemplate(name string) bool {
+	return strings.HasPrefix(path.Base(name), "_") || path.Ext(name) == ".tpl"
+}
+
+// injectNamedTemplate replaces the templates of the chart tested with the partial ones kept and
+// the one including the named template, the chart given should be a copy
+func (t *TestJob) injectNamedTemplate(targetChart *chart.Chart) error {
+	if t.NamedTemplate == nil {
+		return nil
+	}
+	content, err := t.NamedTemplate.content()
+	if err != nil {
+		return err
+	}
+	testedChart, err := chartOfRoute(targetChart, t.chartRoute)
+	if err != nil {
+		return err
+	}
+
+	templates := make([]*chart.Template, 0, len(testedChart.Templates)+1)
+	for _, template := range testedChart.Templates {
+		if isPartialTemplate(template.Name) {
+			templates = append(templates, template)
+		}
+	}
+	testedChart.Templates = append(templates, &chart.Template{
+		Name: path.Join("templates", namedTemplateFile),
+		Data: []byte(content),
+	})
+	return nil
+}
+
+// injectHelm3NamedTemplate is injectNamedTemplate for the charts rendered with helm 3
+func (t *TestJob) injectHelm3NamedTemplate(helm3Chart *v3chart.Chart) error {
+	if t.NamedTemplate == nil {
+		return nil
+	}
+	content, err := t.NamedTemplate.content()
+	if err != nil {
+		return err
+	}
+	testedChart, err := helm3ChartOfRoute(helm3Chart, t.chartRoute)
+	if err != nil {
+		return err
+	}
+
+	templates := make([]*v3chart.File, 0, len(testedChart.Templates)+1)
+	for _, template := range testedChart.Templates {
+		if isPartialTemplate(template.Name) {
+			templates = append(templates, template)
+		}
+	}
+	testedChart.Templates = append(templates, &v3chart.File{
+		Name: path.Join("templates", namedTemplateFile),
+		Data: []byte(content),
+	})
+	return nil
+}
+
+// wrapNamedTemplateOutput makes the output of named template a document to assert, which is
+// the output itself if it's a YAML mapping, otherwise the output string in the `output` field
+func (t *TestJob) wrapNamedTemplateOutput(outputOfFiles map[string]string) error {
+	if t.NamedTemplate == nil {
+		return nil
+	}
+	file := filepath.ToSlash(filepath.Join(t.chartRoute, "templates", namedTemplateFile))
+	output := outputOfFiles[file]
+
+	mapping := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(output), &mapping); err == nil && len(mapping) > 0 {
+		return nil
+	}
+	wrapped, err := yaml.Marshal(map[string]string{"output": output})
+	if err != nil {
+		return err
+	}
+	outputOfFiles[file] = string(wrapped)
+	return nil
+}
@@ -0,0 +1,106 @@
+package schema
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	"github.com/xeipuuv/gojsonschema"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const crdKind = "CustomResourceDefinition"
+
+// AddCustomResourceDefinitions add the CRDs in the multi documents yaml content,
+// documents of other kinds are ignored
+func (v *Validator) AddCustomResourceDefinitions(content []byte) error {
+	decoder := yaml.NewDecoder(strings.NewReader(string(content)))
+	for {
+		manifest := make(common.K8sManifest)
+		if err := decoder.Decode(manifest); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := v.AddCustomResourceDefinition(manifest); err != nil {
+			return err
+		}
+	}
+}
+
+// AddCustomResourceDefinition add the openAPIV3Schema of all versions defined in the CRD manifest,
+// so that the custom resources are validated with it. It's ignored if the manifest is not a CRD
+func (v *Validator) AddCustomResourceDefinition(manifest common.K8sManifest) error {
+	if kind, _ := manifest["kind"].(string); kind != crdKind {
+		return nil
+	}
+
+	crd, _ := common.ConvertToJSONCompatible(manifest).(map[string]interface{})
+	spec, _ := crd["spec"].(map[string]interface{})
+	group, _ := spec["group"].(string)
+	names, _ := spec["names"].(map[string]interface{})
+	kind, _ := names["kind"].(string)
+	if group == "" || kind == "" {
+		return fmt.Errorf("invalid %s, spec.group and spec.names.kind are required", crdKind)
+	}
+
+	// apiextensions.k8s.io/v1beta1 defines the schema for all versions in spec.validation
+	var commonSchema interface{}
+	if validation, ok := spec["validation"].(map[string]interface{}); ok {
+		commonSchema = validation["openAPIV3Schema"]
+	}
+
+	versionSchemas := map[string]interface{}{}
+	if version, ok := spec["version"].(string); ok {
+		versionSchemas[version] = commonSchema
+	}
+	if versions, ok := spec["versions"].([]interface{}); ok {
+		for _, ele := range versions {
+			version, _ := ele.(map[string]interface{})
+			name, _ := version["name"].(string)
+			if name == "" {
+				continue
+			}
+			versionSchemas[name] = commonSchema
+			if versionSchema, ok := version["schema"].(map[string]interface{}); ok {
+				if openAPIV3Schema, ok := versionSchema["openAPIV3Schema"]; ok {
+					versionSchemas[name] = openAPIV3Schema
+				}
+			}
+		}
+	}
+
+	if v.customResourceSchemas == nil {
+		v.customResourceSchemas = map[string]*gojsonschema.Schema{}
+	}
+	for version, openAPIV3Schema := range versionSchemas {
+		key := customResourceKey(group+"/"+version, kind)
+		// custom resources of CRD without schema are not validated
+		if openAPIV3Schema == nil {
+			v.customResourceSchemas[key] = nil
+			continue
+		}
+		schema, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(openAPIV3Schema))
+		if err != nil {
+			return fmt.Errorf("failed to load openAPIV3Schema of %s %s: %s", group+"/"+version, kind, err)
+		}
+		v.customResourceSchemas[key] = schema
+	}
+	return nil
+}
+
+// Copy returns a copy of validator, the CRDs added to the copy don't affect the original one
+func (v *Validator) Copy() *Validator {
+	copied := *v
+	copied.customResourceSchemas = make(map[string]*gojsonschema.Schema, len(v.customResourceSchemas))
+	for key, schema := range v.customResourceSchemas {
+		copied.customResourceSchemas[key] = schema
+	}
+	return &copied
+}
+
+func customResourceKey(apiVersion, kind string) string {
+	return apiVersion + "/" + kind
+}
@@ -0,0 +1,304 @@
+package schema
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Violation a violation of schema found in manifest
+type Violation struct {
+	// JSON pointer of the field violated, like "/spec/template/spec/containers/0"
+	Pointer string
+	Message string
+	// the kube version of the schema validated with like "v1.16.0",
+	// set only if the schema of Validator.KubeVersion is not available and the nearest one is used
+	SchemaVersion string
+}
+
+func (v Violation) String() string {
+	if v.SchemaVersion != "" {
+		return fmt.Sprintf("%s: %s (with the schema of kubernetes %s)", v.Pointer, v.Message, v.SchemaVersion)
+	}
+	return fmt.Sprintf("%s: %s", v.Pointer, v.Message)
+}
+
+// Validator validate manifests with the json schemas found in Locations for KubeVersion
+type Validator struct {
+	// directories of schemas, in the layout of `<version>-standalone-strict/<kind>-<group>-<version>.json`,
+	// or with the schema files `<kind>-<group>-<version>.json` directly under it
+	Locations []string
+	// like "1.14" or "v1.14.3"
+	KubeVersion string
+	// openAPIV3Schema of custom resources added from CRDs, keyed by apiVersion and kind
+	customResourceSchemas map[string]*gojsonschema.Schema
+}
+
+var compiledSchemas = map[string]*gojsonschema.Schema{}
+var compiledSchemasLock sync.Mutex
+
+var versionNumberPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// the schemas of a version at most this many minors away are used if the ones of kube version not available
+const maxFallbackMinorDistance = 2
+
+// like "v1.14.0-standalone-strict", "v1.14.0-standalone" or "v1.14.0"
+var versionDirPattern = regexp.MustCompile(`^v(\d+)\.(\d+)\.(\d+)(?:-standalone(?:-strict)?)?$`)
+
+// DefaultLocation returns the `schemas` directory bundled with the plugin, or empty string if not existed
+func DefaultLocation() string {
+	candidates := []string{}
+	if pluginDir := os.Getenv("HELM_PLUGIN_DIR"); pluginDir != "" {
+		candidates = append(candidates, filepath.Join(pluginDir, "schemas"))
+	}
+	if executable, err := os.Executable(); err == nil {
+		candidates = append(candidates, filepath.Join(filepath.Dir(executable), "schemas"))
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// ValidateManifest validate the manifest with the schema of its apiVersion and kind
+func (v *Validator) ValidateManifest(manifest common.K8sManifest) ([]Violation, error) {
+	apiVersion, _ := manifest["apiVersion"].(string)
+	kind, _ := manifest["kind"].(string)
+	if apiVersion == "" || kind == "" {
+		return []Violation{{Pointer: "", Message: "apiVersion and kind are required"}}, nil
+	}
+
+	schema, schemaVersion, err := v.schemaOf(apiVersion, kind)
+	if err != nil {
+		return nil, err
+	}
+	if schema == nil {
+		return []Violation{}, nil
+	}
+
+	result, err := schema.Validate(gojsonschema.NewGoLoader(common.ConvertToJSONCompatible(manifest)))
+	if err != nil {
+		return nil, err
+	}
+
+	violations := violationsOf(result)
+	if schemaVersion != "" && schemaVersion != normalizeVersion(v.KubeVersion) {
+		for idx := range violations {
+			violations[idx].SchemaVersion = schemaVersion
+		}
+	}
+	return violations, nil
+}
+
+// schemaOf returns the schema of custom resource if its CRD added, or load the schema from Locations,
+// with the kube version of the schema if it's found in a version directory
+func (v *Validator) schemaOf(apiVersion, kind string) (*gojsonschema.Schema, string, error) {
+	if schema, ok := v.customResourceSchemas[customResourceKey(apiVersion, kind)]; ok {
+		return schema, "", nil
+	}
+	return v.loadSchema(apiVersion, kind)
+}
+
+// loadSchema find the schema file of apiVersion and kind in Locations and compile it
+func (v *Validator) loadSchema(apiVersion, kind string) (*gojsonschema.Schema, string, error) {
+	var schemaFile schemaFileCandidate
+	for _, candidate := range v.schemaFileCandidates(apiVersion, kind) {
+		if _, err := os.Stat(candidate.path); err == nil {
+			schemaFile = candidate
+			break
+		}
+	}
+	if len(v.Locations) == 0 {
+		return nil, "", fmt.Errorf(
+			"no kubernetes json schemas to validate %s %s with, the plugin is installed without the schemas "+
+				"bundled, reinstall it with them or give the directory of schemas with --schema-location",
+			apiVersion, kind,
+		)
+	}
+	if schemaFile.path == "" {
+		return nil, "", fmt.Errorf(
+			"schema of %s %s for kubernetes %s not found in [%s], "+
+				"give the directory of schemas for the version with --schema-location",
+			apiVersion, kind, v.KubeVersion, strings.Join(v.Locations, ", "),
+		)
+	}
+
+	compiledSchemasLock.Lock()
+	defer compiledSchemasLock.Unlock()
+	if schema, ok := compiledSchemas[schemaFile.path]; ok {
+		return schema, schemaFile.version, nil
+	}
+
+	absPath, err := filepath.Abs(schemaFile.path)
+	if err != nil {
+		return nil, "", err
+	}
+	schema, err := gojsonschema.NewSchema(
+		gojsonschema.NewReferenceLoader("file://" + filepath.ToSlash(absPath)),
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load schema %s: %s", schemaFile.path, err)
+	}
+	compiledSchemas[schemaFile.path] = schema
+	return schema, schemaFile.version, nil
+}
+
+// schemaFileCandidate a possible schema file path, and the kube version of its directory if any
+type schemaFileCandidate struct {
+	path    string
+	version string
+}
+
+// schemaFileCandidates the possible schema file paths of apiVersion and kind,
+// in the directories of the nearest kube version available in each location
+func (v *Validator) schemaFileCandidates(apiVersion, kind string) []schemaFileCandidate {
+	fileName := schemaFileName(apiVersion, kind)
+	version := normalizeVersion(v.KubeVersion)
+
+	candidates := []schemaFileCandidate{}
+	for _, location := range v.Locations {
+		if nearestVersion := nearestVersionIn(location, version); nearestVersion != "" {
+			for _, versionDir := range []string{
+				nearestVersion + "-standalone-strict",
+				nearestVersion + "-standalone",
+				nearestVersion,
+			} {
+				candidates = append(candidates, schemaFileCandidate{
+					path:    filepath.Join(location, versionDir, fileName),
+					version: nearestVersion,
+				})
+			}
+		}
+		candidates = append(candidates, schemaFileCandidate{path: filepath.Join(location, fileName)})
+	}
+	return candidates
+}
+
+// nearestVersionIn returns version if its schemas existed in location, otherwise the nearest version
+// of the same major in location like "v1.16.0", the older one if two are equally near. Empty string
+// is returned if none is within maxFallbackMinorDistance, whose schemas may miss the newer fields
+func nearestVersionIn(location string, version string) string {
+	if version == "" {
+		return ""
+	}
+	entries, err := ioutil.ReadDir(location)
+	if err != nil {
+		return version
+	}
+	wanted := versionNumberPattern.FindStringSubmatch(version)
+
+	nearest := ""
+	nearestDistance := -1
+	for _, entry := range entries {
+		matched := versionDirPattern.FindStringSubmatch(entry.Name())
+		if !entry.IsDir() || matched == nil || matched[1] != wanted[1] {
+			continue
+		}
+		available := "v" + matched[1] + "." + matched[2] + "." + matched[3]
+		if available == version {
+			return version
+		}
+		if abs(atoi(wanted[2])-atoi(matched[2])) > maxFallbackMinorDistance {
+			continue
+		}
+		distance := versionDistance(wanted[2], wanted[3], matched[2], matched[3])
+		if nearestDistance < 0 || distance < nearestDistance ||
+			(distance == nearestDistance && compareVersions(available, nearest) < 0) {
+			nearest, nearestDistance = available, distance
+		}
+	}
+	return nearest
+}
+
+// versionDistance the distance between the minor and patch versions, minors weigh more than any patch
+func versionDistance(minor, patch, otherMinor, otherPatch string) int {
+	return abs(atoi(minor)-atoi(otherMinor))*10000 + abs(atoi(patch)-atoi(otherPatch))
+}
+
+// compareVersions compares the minor and patch of versions like "v1.14.0" of the same major
+func compareVersions(version, other string) int {
+	matched := versionNumberPattern.FindStringSubmatch(version)
+	otherMatched := versionNumberPattern.FindStringSubmatch(other)
+	if diff := atoi(matched[2]) - atoi(otherMatched[2]); diff != 0 {
+		return diff
+	}
+	return atoi(matched[3]) - atoi(otherMatched[3])
+}
+
+func atoi(number string) int {
+	value, _ := strconv.Atoi(number)
+	return value
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+// schemaFileName the file name of schema, like "deployment-apps-v1.json",
+// "ingress-networking-v1beta1.json" for "networking.k8s.io/v1beta1" or "pod-v1.json" for core group
+func schemaFileName(apiVersion, kind string) string {
+	parts := []string{strings.ToLower(kind)}
+	if slash := strings.Index(apiVersion, "/"); slash >= 0 {
+		group := strings.Split(apiVersion[:slash], ".")[0]
+		parts = append(parts, strings.ToLower(group), strings.ToLower(apiVersion[slash+1:]))
+	} else {
+		parts = append(parts, strings.ToLower(apiVersion))
+	}
+	return strings.Join(parts, "-") + ".json"
+}
+
+// normalizeVersion format kube version like "1.14", "1.14+" or "v1.14.3" as "v1.14.0" or "v1.14.3"
+func normalizeVersion(kubeVersion string) string {
+	matched := versionNumberPattern.FindStringSubmatch(kubeVersion)
+	if matched == nil {
+		return ""
+	}
+	patch := matched[3]
+	if patch == "" {
+		patch = "0"
+	}
+	return fmt.Sprintf("v%s.%s.%s", matched[1], matched[2], patch)
+}
+
+// violationsOf collects the violations in the result of validation
+func violationsOf(result *gojsonschema.Result) []Violation {
+	violations := make([]Violation, 0, len(result.Errors()))
+	for _, resultError := range result.Errors() {
+		violations = append(violations, Violation{
+			Pointer: jsonPointerOf(resultError),
+			Message: resultError.Description(),
+		})
+	}
+	return violations
+}
+
+// jsonPointerOf build JSON pointer of the field where the error occurred
+func jsonPointerOf(resultError gojsonschema.ResultError) string {
+	tokens := strings.Split(resultError.Context().String("\x00"), "\x00")[1:]
+	if property, ok := resultError.Details()["property"].(string); ok {
+		switch resultError.Type() {
+		case "required", "additional_property_not_allowed":
+			tokens = append(tokens, property)
+		}
+	}
+
+	escaper := strings.NewReplacer("~", "~0", "/", "~1")
+	pointer := ""
+	for _, token := range tokens {
+		pointer += "/" + escaper.Replace(token)
+	}
+	return pointer
+}
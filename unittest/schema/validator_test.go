@@ -0,0 +1,304 @@
+package schema_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	. "github.com/lrills/helm-unittest/unittest/schema"
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var deploymentSchema = `{
+  "type": "object",
+  "required": ["apiVersion", "kind", "spec"],
+  "additionalProperties": false,
+  "properties": {
+    "apiVersion": {"type": "string"},
+    "kind": {"type": "string"},
+    "metadata": {"type": "object"},
+    "spec": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "replicas": {"type": "integer"},
+        "template": {
+          "type": "object",
+          "properties": {
+            "spec": {
+              "type": "object",
+              "properties": {
+                "containers": {
+                  "type": "array",
+                  "items": {
+                    "type": "object",
+                    "additionalProperties": false,
+                    "properties": {
+                      "name": {"type": "string"},
+                      "image": {"type": "string"},
+                      "imagePullPolicy": {"type": "string"}
+                    }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+func makeSchemaLocation(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "schema_validator_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	versionDir := filepath.Join(dir, "v1.14.0-standalone-strict")
+	if err := os.MkdirAll(versionDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	schemaFile := filepath.Join(versionDir, "deployment-apps-v1.json")
+	if err := ioutil.WriteFile(schemaFile, []byte(deploymentSchema), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func makeManifest(doc string) common.K8sManifest {
+	manifest := common.K8sManifest{}
+	yaml.Unmarshal([]byte(doc), &manifest)
+	return manifest
+}
+
+func TestValidateManifestWhenValid(t *testing.T) {
+	validator := Validator{Locations: []string{makeSchemaLocation(t)}, KubeVersion: "1.14"}
+	violations, err := validator.ValidateManifest(makeManifest(`
+apiVersion: apps/v1
+kind: Deployment
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx
+          imagePullPolicy: Always
+`))
+
+	a := assert.New(t)
+	a.Nil(err)
+	a.Equal([]Violation{}, violations)
+}
+
+func TestValidateManifestWhenInvalid(t *testing.T) {
+	validator := Validator{Locations: []string{makeSchemaLocation(t)}, KubeVersion: "v1.14.0"}
+	violations, err := validator.ValidateManifest(makeManifest(`
+apiVersion: apps/v1
+kind: Deployment
+spec:
+  replicas: one
+  imagePullPolicy: Always
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx
+          pullPolicy: Always
+`))
+
+	a := assert.New(t)
+	a.Nil(err)
+	pointers := make([]string, len(violations))
+	for idx, violation := range violations {
+		pointers[idx] = violation.Pointer
+	}
+	a.ElementsMatch([]string{
+		"/spec/replicas",
+		"/spec/imagePullPolicy",
+		"/spec/template/spec/containers/0/pullPolicy",
+	}, pointers)
+}
+
+func TestValidateManifestWithNearestKubeVersion(t *testing.T) {
+	location := makeSchemaLocation(t)
+	otherVersionDir := filepath.Join(location, "v1.10.0-standalone-strict")
+	if err := os.MkdirAll(otherVersionDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	a := assert.New(t)
+	for kubeVersion, found := range map[string]bool{
+		"1.9":  false, // nearest v1.10.0 has no deployment schema
+		"1.12": false, // as near as v1.14.0, the older v1.10.0 taken
+		"1.13": true,
+		"1.16": true,
+		"1.17": false, // v1.14.0 too far to fall back to
+	} {
+		validator := Validator{Locations: []string{location}, KubeVersion: kubeVersion}
+		_, err := validator.ValidateManifest(makeManifest(`
+apiVersion: apps/v1
+kind: Deployment
+`))
+		if found {
+			a.Nil(err, kubeVersion)
+		} else {
+			a.EqualError(err, "schema of apps/v1 Deployment for kubernetes "+kubeVersion+" not found in ["+location+"]"+
+				", give the directory of schemas for the version with --schema-location")
+		}
+	}
+}
+
+func TestValidateManifestWithNearestKubeVersionReported(t *testing.T) {
+	validator := Validator{Locations: []string{makeSchemaLocation(t)}, KubeVersion: "1.15"}
+	violations, err := validator.ValidateManifest(makeManifest(`
+apiVersion: apps/v1
+kind: Deployment
+spec:
+  replicas: one
+`))
+
+	a := assert.New(t)
+	a.Nil(err)
+	a.Len(violations, 1)
+	a.Equal("v1.14.0", violations[0].SchemaVersion)
+	a.Contains(violations[0].String(), "(with the schema of kubernetes v1.14.0)")
+}
+
+func TestValidateManifestWhenSchemaNotFound(t *testing.T) {
+	location := makeSchemaLocation(t)
+	validator := Validator{Locations: []string{location}, KubeVersion: "1.14"}
+	_, err := validator.ValidateManifest(makeManifest(`
+apiVersion: v1
+kind: Service
+`))
+
+	a := assert.New(t)
+	a.EqualError(err, "schema of v1 Service for kubernetes 1.14 not found in ["+location+"]"+
+		", give the directory of schemas for the version with --schema-location")
+}
+
+var crdToTestCustomResource = `
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: crontabs.stable.example.com
+spec:
+  group: stable.example.com
+  versions:
+    - name: v1
+      served: true
+      storage: true
+  names:
+    kind: CronTab
+  validation:
+    openAPIV3Schema:
+      properties:
+        spec:
+          properties:
+            cronSpec:
+              type: string
+            replicas:
+              type: integer
+          required:
+            - cronSpec
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: backups.stable.example.com
+spec:
+  group: stable.example.com
+  names:
+    kind: Backup
+  versions:
+    - name: v1
+      schema:
+        openAPIV3Schema:
+          type: object
+          properties:
+            spec:
+              type: object
+              additionalProperties: false
+              properties:
+                schedule:
+                  type: string
+    - name: v2alpha1
+`
+
+func TestValidateManifestOfCustomResource(t *testing.T) {
+	validator := Validator{KubeVersion: "1.14"}
+
+	a := assert.New(t)
+	a.Nil(validator.AddCustomResourceDefinitions([]byte(crdToTestCustomResource)))
+
+	violations, err := validator.ValidateManifest(makeManifest(`
+apiVersion: stable.example.com/v1
+kind: CronTab
+spec:
+  cronSpec: "* * * * */5"
+  replicas: 1
+`))
+	a.Nil(err)
+	a.Equal([]Violation{}, violations)
+
+	violations, err = validator.ValidateManifest(makeManifest(`
+apiVersion: stable.example.com/v1
+kind: CronTab
+spec:
+  replicas: one
+`))
+	a.Nil(err)
+	pointers := make([]string, len(violations))
+	for idx, violation := range violations {
+		pointers[idx] = violation.Pointer
+	}
+	a.ElementsMatch([]string{"/spec/cronSpec", "/spec/replicas"}, pointers)
+
+	violations, err = validator.ValidateManifest(makeManifest(`
+apiVersion: stable.example.com/v1
+kind: Backup
+spec:
+  schedul: daily
+`))
+	a.Nil(err)
+	a.Equal([]Violation{{
+		Pointer: "/spec/schedul",
+		Message: "Additional property schedul is not allowed",
+	}}, violations)
+
+	violations, err = validator.ValidateManifest(makeManifest(`
+apiVersion: stable.example.com/v2alpha1
+kind: Backup
+spec:
+  anything: goes
+`))
+	a.Nil(err)
+	a.Equal([]Violation{}, violations)
+}
+
+func TestCopyValidatorWithCustomResourceAdded(t *testing.T) {
+	validator := Validator{KubeVersion: "1.14"}
+	copied := validator.Copy()
+
+	a := assert.New(t)
+	a.Nil(copied.AddCustomResourceDefinitions([]byte(crdToTestCustomResource)))
+
+	manifest := makeManifest(`
+apiVersion: stable.example.com/v1
+kind: CronTab
+spec:
+  replicas: 1
+`)
+	violations, err := copied.ValidateManifest(manifest)
+	a.Nil(err)
+	a.Len(violations, 1)
+
+	_, err = validator.ValidateManifest(manifest)
+	a.EqualError(err, "no kubernetes json schemas to validate stable.example.com/v1 CronTab with, "+
+		"the plugin is installed without the schemas bundled, "+
+		"reinstall it with them or give the directory of schemas with --schema-location")
+}
@@ -0,0 +1,20 @@
+package schema
+
+import (
+	"github.com/lrills/helm-unittest/unittest/common"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// ValidateValues validate the values of chart with the JSON schema content, like values.schema.json
+func ValidateValues(schemaContent []byte, values interface{}) ([]Violation, error) {
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(schemaContent))
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := schema.Validate(gojsonschema.NewGoLoader(common.ConvertToJSONCompatible(values)))
+	if err != nil {
+		return nil, err
+	}
+	return violationsOf(result), nil
+}
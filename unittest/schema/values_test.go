@@ -0,0 +1,30 @@
+package schema_test
+
+import (
+	"testing"
+
+	. "github.com/lrills/helm-unittest/unittest/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateValues(t *testing.T) {
+	valuesSchema := `{
+  "type": "object",
+  "required": ["image"],
+  "properties": {
+    "replicaCount": {"type": "integer", "minimum": 1}
+  }
+}`
+
+	violations, err := ValidateValues([]byte(valuesSchema), map[string]interface{}{"replicaCount": 0})
+
+	a := assert.New(t)
+	a.Nil(err)
+	a.ElementsMatch([]Violation{
+		{Pointer: "/image", Message: "image is required"},
+		{Pointer: "/replicaCount", Message: "Must be greater than or equal to 1"},
+	}, violations)
+
+	_, err = ValidateValues([]byte("{"), map[string]interface{}{})
+	a.NotNil(err)
+}
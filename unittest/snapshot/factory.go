@@ -6,12 +6,13 @@ import (
 	"path/filepath"
 )
 
-const snapshotDirName = "__snapshot__"
+// DirName the name of directory to cache snapshots in
+const DirName = "__snapshot__"
 const snapshotFileExt = ".snap"
 
 // CreateSnapshotOfSuite retruns snapshot.Cache for suite file, create `__snapshot__` dir if not existed
 func CreateSnapshotOfSuite(path string, isUpdating bool) (*Cache, error) {
-	cacheDir := filepath.Join(filepath.Dir(path), snapshotDirName)
+	cacheDir := filepath.Join(filepath.Dir(path), DirName)
 	if err := ensureDir(cacheDir); err != nil {
 		return nil, err
 	}
@@ -27,6 +28,28 @@ func CreateSnapshotOfSuite(path string, isUpdating bool) (*Cache, error) {
 	return cache, nil
 }
 
+// CreateSnapshotAt retruns snapshot.Cache stored at cacheFilePath, for the suites not located on
+// a writable place like the ones in packaged charts, the directories are created if not existed
+func CreateSnapshotAt(cacheFilePath string, isUpdating bool) (*Cache, error) {
+	if err := os.MkdirAll(filepath.Dir(cacheFilePath), os.ModePerm); err != nil {
+		return nil, err
+	}
+	cache := &Cache{
+		Filepath:   cacheFilePath,
+		IsUpdating: isUpdating,
+	}
+
+	if err := cache.RestoreFromFile(); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+// SnapshotFileOf returns the path of cache file of suite under dir, like `dir/path/my_test.yaml.snap`
+func SnapshotFileOf(dir, path string) string {
+	return filepath.Join(dir, path+snapshotFileExt)
+}
+
 func ensureDir(path string) error {
 	info, err := os.Stat(path)
 	if err != nil {
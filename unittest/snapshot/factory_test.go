@@ -82,3 +82,21 @@ func TestCreateSnapshotOfSuiteWhenCacheFileExisted(t *testing.T) {
 
 	os.RemoveAll(dir)
 }
+
+func TestCreateSnapshotAtWhenNoCacheDir(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "test")
+	cacheFile := SnapshotFileOf(filepath.Join(dir, "snapshots"), filepath.Join("my-chart", "tests", "service_test.yaml"))
+	cache, err := CreateSnapshotAt(cacheFile, true)
+
+	a := assert.New(t)
+	a.Nil(err)
+	a.Equal(filepath.Join(dir, "snapshots", "my-chart", "tests", "service_test.yaml.snap"), cache.Filepath)
+	a.True(cache.IsUpdating)
+	a.False(cache.Existed)
+
+	info, err := os.Stat(filepath.Join(dir, "snapshots", "my-chart", "tests"))
+	a.Nil(err)
+	a.True(info.IsDir())
+
+	os.RemoveAll(dir)
+}
@@ -0,0 +1,78 @@
+package unittest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v3chart "helm.sh/helm/v3/pkg/chart"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// templateOverridesFile the partial template injected in the chart to define the named templates
+// overridden. The engine parses the templates of fewer path segments later, so the defines in it,
+// placed at the root of chart, replace the ones in templates and dependencies
+const templateOverridesFile = "_template_overrides.tpl"
+
+// templateOverridesOf returns the template defining the named templates with the stub bodies,
+// nil if nothing overridden
+func templateOverridesOf(overrides map[string]string) *chart.Template {
+	if len(overrides) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var content strings.Builder
+	for _, name := range names {
+		// the empty action keeps an empty stub from being ignored as an empty redefinition
+		fmt.Fprintf(&content, "{{- define %q }}{{ \"\" }}%s{{ end -}}\n", name, overrides[name])
+	}
+	return &chart.Template{Name: templateOverridesFile, Data: []byte(content.String())}
+}
+
+// withTemplateOverrides returns the templates with the overrides template added or replaced
+func withTemplateOverrides(templates []*chart.Template, overrides *chart.Template) []*chart.Template {
+	replaced := make([]*chart.Template, 0, len(templates)+1)
+	for _, template := range templates {
+		if template.Name != templateOverridesFile {
+			replaced = append(replaced, template)
+		}
+	}
+	return append(replaced, overrides)
+}
+
+// withHelm3TemplateOverrides is withTemplateOverrides for the charts rendered with helm 3
+func withHelm3TemplateOverrides(templates []*v3chart.File, overrides *chart.Template) []*v3chart.File {
+	replaced := make([]*v3chart.File, 0, len(templates)+1)
+	for _, template := range templates {
+		if template.Name != templateOverridesFile {
+			replaced = append(replaced, template)
+		}
+	}
+	return append(replaced, &v3chart.File{Name: overrides.Name, Data: overrides.Data})
+}
+
+// templateOverrides returns the overrides template of suite merged with the ones of test,
+// nil if the test overrides nothing so the one prepared with suite is used
+func (t *TestJob) templateOverrides() *chart.Template {
+	if len(t.TemplateOverrides) == 0 {
+		return nil
+	}
+	return templateOverridesOf(t.mergedTemplateOverrides())
+}
+
+// mergedTemplateOverrides returns the stub bodies of suite and test, the ones of test take precedence
+func (t *TestJob) mergedTemplateOverrides() map[string]string {
+	merged := make(map[string]string, len(t.suiteTemplateOverrides)+len(t.TemplateOverrides))
+	for name, body := range t.suiteTemplateOverrides {
+		merged[name] = body
+	}
+	for name, body := range t.TemplateOverrides {
+		merged[name] = body
+	}
+	return merged
+}
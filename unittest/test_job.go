@@ -4,15 +4,23 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
 	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
+	"unicode"
 
 	"github.com/lrills/helm-unittest/unittest/common"
+	"github.com/lrills/helm-unittest/unittest/schema"
 	"github.com/lrills/helm-unittest/unittest/snapshot"
 	"github.com/lrills/helm-unittest/unittest/validators"
 	"github.com/lrills/helm-unittest/unittest/valueutils"
 	yaml "gopkg.in/yaml.v2"
+	v3chart "helm.sh/helm/v3/pkg/chart"
 	"k8s.io/helm/pkg/chartutil"
 	"k8s.io/helm/pkg/engine"
 	"k8s.io/helm/pkg/proto/hapi/chart"
@@ -36,16 +44,33 @@ type TestJob struct {
 	Values     []string
 	Set        map[string]interface{}
 	Assertions []*Assertion `yaml:"asserts"`
-	Release    struct {
+	// the objects existing in cluster returned by `lookup`, besides the ones of suite
+	KubernetesProvider *KubernetesProvider `yaml:"kubernetesProvider"`
+	// the files of chart added or replaced, which are read by `.Files` in templates
+	Files []*ChartFile
+	// the stub bodies of named templates defined in chart, besides the ones of suite
+	TemplateOverrides map[string]string `yaml:"templateOverrides"`
+	// the named template to render and assert, instead of the templates of chart
+	NamedTemplate *NamedTemplate `yaml:"namedTemplate"`
+	Release       struct {
 		Name      string
 		Namespace string
 		Revision  int
 		IsUpgrade bool
+		// whether the release is rolled back, which runs the rollback hooks
+		IsRollback bool `yaml:"isRollback"`
+		// the release time frozen like "2020-01-01T00:00:00Z", also returned by `now` in templates
+		Time string
 	}
 	Capabilities struct {
-		APIVersions []string
+		// the api versions like "apps/v1", or resource-level ones like "apps/v1/Deployment"
+		APIVersions      []string `yaml:"apiVersions"`
 		KubeVersionMajor string
 		KubeVersionMinor string
+		// the full kube version like "v1.27.3"
+		KubeVersion string `yaml:"kubeVersion"`
+		// the file of api versions dumped by `kubectl api-versions`, relative to the suite file
+		APIVersionsFile string `yaml:"apiVersionsFile"`
 	}
 	// route indicate which chart in the dependency hierarchy
 	// like "parant-chart", "parent-charts/charts/child-chart"
@@ -54,6 +79,40 @@ type TestJob struct {
 	definitionFile string
 	// template assertion should assert if not specified
 	defaultTemplateToAssert string
+	// rego policies used by matchPolicy assertions
+	policies []string
+	// directories of kubernetes schemas used to validate manifests
+	schemaLocations []string
+	// whether to validate all manifests rendered with kubernetes schemas
+	validateSchema bool
+	// files or directories of CRDs to validate custom resources
+	crdFiles []string
+	// the schemas of CRDs in the chart and crdFiles, loaded once for all tests of suite
+	crdSchemas *crdSchemaCache
+	// kube version given in cli, used if not set in capabilities,
+	// and the deprecated APIs of all manifests are checked if given
+	defaultKubeVersion string
+	// best practice rules checked on all manifests, nil if not enabled
+	bestPractices *validators.BestPracticesValidator
+	// the chart loaded with helm 3 to render with, nil if rendered with helm 2
+	helm3Chart *v3chart.Chart
+	// the files packaged with the test in chart archive by their paths, nil if not packaged
+	archivedFiles map[string][]byte
+	// whether to render with the random and crypto functions seeded or stubbed
+	deterministic bool
+	// the objects existing in cluster declared in suite
+	suiteKubernetesProvider *KubernetesProvider
+	// the capabilities shared by tests of suite
+	suiteCapabilities *CapabilitiesConfig
+	// the stub bodies of named templates overridden in suite
+	suiteTemplateOverrides map[string]string
+	// file of api versions given in cli, used if not set in capabilities of test or suite
+	defaultAPIVersionsFile string
+	// the kube version and api versions resolved from the capabilities of test, suite and cli
+	resolvedKubeVersion string
+	resolvedAPIVersions []string
+	// the previous version of chart to check the upgrade safety against, given in cli
+	previous *previousChart
 }
 
 // Run render the chart and validate it with assertions in TestJob
@@ -71,27 +130,156 @@ func (t *TestJob) Run(
 		return result
 	}
 
-	outputOfFiles, err := t.renderChart(targetChart, userValues)
-	if err != nil {
+	if _, err := t.releaseTime(); err != nil {
+		result.ExecError = err
+		return result
+	}
+
+	if err := t.resolveCapabilities(); err != nil {
+		result.ExecError = err
+		return result
+	}
+
+	outputOfFiles, renderedValues, err := t.renderChart(targetChart, userValues)
+	valuesSchemaViolations := []string{}
+	if schemaErr, ok := err.(*valuesSchemaError); ok && t.assertsValuesSchema() {
+		// nothing rendered as helm does, the violations are left to be asserted
+		valuesSchemaViolations = schemaErr.violations
+	} else if err != nil {
 		result.ExecError = err
 		return result
 	}
 
+	if outputOfFiles != nil {
+		renderNothingForDisabledDependencies(targetChart, t.helm3Chart, outputOfFiles)
+		if err := t.wrapNamedTemplateOutput(outputOfFiles); err != nil {
+			result.ExecError = err
+			return result
+		}
+	}
+
 	manifestsOfFiles, err := t.parseManifestsFromOutputOfFiles(outputOfFiles)
 	if err != nil {
 		result.ExecError = err
 		return result
 	}
 
+	// the CRDs are not loaded unless the manifests are validated with schemas
+	var schemaValidator validators.SchemaValidator
+	if t.validateSchema || t.assertsValidManifest() {
+		if schemaValidator, err = t.schemaValidator(targetChart, manifestsOfFiles); err != nil {
+			result.ExecError = err
+			return result
+		}
+	}
+
+	var previousManifestsOfFiles map[string][]common.K8sManifest
+	if t.previous != nil && outputOfFiles != nil && t.NamedTemplate == nil {
+		if previousManifestsOfFiles, err = t.renderPreviousChart(userValues); err != nil {
+			result.ExecError = err
+			return result
+		}
+	}
+
 	snapshotComparer := &orderedSnapshotComparer{cache: cache, test: t.Name}
 	result.Passed, result.AssertsResult = t.runAssertions(
 		manifestsOfFiles,
+		renderedValues,
 		snapshotComparer,
+		schemaValidator,
+		valuesSchemaViolations,
+		previousManifestsOfFiles,
 	)
 
+	// the output of named template is not a manifest to check
+	if t.validateSchema && t.NamedTemplate == nil {
+		schemaPassed, schemaResults := t.validateManifestsOfFiles(
+			manifestsOfFiles,
+			"isValidManifest",
+			"schema validation",
+			validators.IsValidManifestValidator{},
+			validators.ValidateContext{SchemaValidator: schemaValidator},
+			len(result.AssertsResult),
+		)
+		result.Passed = result.Passed && schemaPassed
+		result.AssertsResult = append(result.AssertsResult, schemaResults...)
+	}
+
+	if t.defaultKubeVersion != "" && t.NamedTemplate == nil {
+		deprecationPassed, deprecationResults := t.validateManifestsOfFiles(
+			manifestsOfFiles,
+			"notDeprecatedAPI",
+			"deprecated API check",
+			validators.NotDeprecatedAPIValidator{},
+			validators.ValidateContext{KubeVersion: t.kubeVersion()},
+			len(result.AssertsResult),
+		)
+		result.Passed = result.Passed && deprecationPassed
+		result.AssertsResult = append(result.AssertsResult, deprecationResults...)
+	}
+
+	if t.bestPractices != nil && t.NamedTemplate == nil {
+		bestPracticesPassed, bestPracticesResults, err := t.checkBestPractices(
+			manifestsOfFiles,
+			len(result.AssertsResult),
+		)
+		if err != nil {
+			result.ExecError = err
+			return result
+		}
+		result.Passed = result.Passed && bestPracticesPassed
+		result.AssertsResult = append(result.AssertsResult, bestPracticesResults...)
+	}
+
+	if previousManifestsOfFiles != nil {
+		upgradeSafe, failInfo := validators.IsUpgradeSafeValidator{}.Validate(&validators.ValidateContext{
+			AllDocs:      manifestsOfFiles,
+			PreviousDocs: previousManifestsOfFiles,
+		})
+		result.Passed = result.Passed && upgradeSafe
+		result.AssertsResult = append(result.AssertsResult, &AssertionResult{
+			Index:      len(result.AssertsResult),
+			Passed:     upgradeSafe,
+			FailInfo:   failInfo,
+			AssertType: "isUpgradeSafe",
+			CustomInfo: fmt.Sprintf("- upgrade safety check against %s fail", t.previous.source),
+		})
+	}
+
 	return result
 }
 
+const valuesSchemaFile = "values.schema.json"
+
+// valuesSchemaError the values of test violate the values.schema.json of charts
+type valuesSchemaError struct {
+	violations []string
+}
+
+func (e *valuesSchemaError) Error() string {
+	return "values don't meet the values.schema.json of charts:\n\t" + strings.Join(e.violations, "\n\t")
+}
+
+// assertsValuesSchema whether the test asserts the violations of values schema
+func (t *TestJob) assertsValuesSchema() bool {
+	for _, assertion := range t.Assertions {
+		if _, ok := assertion.validator.(*validators.FailedValuesSchemaValidator); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// assertsValidManifest whether the test asserts the manifests valid with schemas
+func (t *TestJob) assertsValidManifest() bool {
+	for _, assertion := range t.Assertions {
+		if _, ok := assertion.validator.(*validators.IsValidManifestValidator); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // liberally borrows from helm-template
 func (t *TestJob) getUserValues() ([]byte, error) {
 	base := map[interface{}]interface{}{}
@@ -106,7 +294,7 @@ func (t *TestJob) getUserValues() ([]byte, error) {
 			valueFilePath = filepath.Join(filepath.Dir(t.definitionFile), specifiedPath)
 		}
 
-		bytes, err := ioutil.ReadFile(valueFilePath)
+		bytes, err := t.readFile(valueFilePath)
 		if err != nil {
 			return []byte{}, err
 		}
@@ -128,34 +316,198 @@ func (t *TestJob) getUserValues() ([]byte, error) {
 	return yaml.Marshal(base)
 }
 
-// render the chart and return result map
-func (t *TestJob) renderChart(targetChart *chart.Chart, userValues []byte) (map[string]string, error) {
+// valuesSchemaViolationsOfChart validates the values of chart and its dependencies with their
+// values.schema.json, the violations are prefixed with the chart name like "my-chart/replicaCount"
+func valuesSchemaViolationsOfChart(targetChart *chart.Chart, values map[string]interface{}) ([]string, error) {
+	violations := make([]string, 0)
+	for _, file := range targetChart.Files {
+		if file.TypeUrl != valuesSchemaFile {
+			continue
+		}
+		schemaViolations, err := valuesSchemaViolations(targetChart.Metadata.Name, file.Value, values)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, schemaViolations...)
+	}
+
+	for _, dependency := range targetChart.Dependencies {
+		dependencyViolations, err := valuesSchemaViolationsOfChart(
+			dependency,
+			valuesOfDependency(values, dependency.Metadata.Name),
+		)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, dependencyViolations...)
+	}
+	return violations, nil
+}
+
+// valuesOfDependency returns the values scoped to the dependency
+func valuesOfDependency(values map[string]interface{}, name string) map[string]interface{} {
+	dependencyValues, _ := common.ConvertToJSONCompatible(values[name]).(map[string]interface{})
+	if dependencyValues == nil {
+		return map[string]interface{}{}
+	}
+	return dependencyValues
+}
+
+// readFile reads the file of path, from the chart archive if the test is packaged in it
+func (t *TestJob) readFile(path string) ([]byte, error) {
+	if t.archivedFiles == nil {
+		return ioutil.ReadFile(path)
+	}
+	content, ok := t.archivedFiles[filepath.Clean(path)]
+	if !ok {
+		return nil, fmt.Errorf("file %s not found in chart archive", path)
+	}
+	return content, nil
+}
+
+// render the chart and return result map, with the values rendered with
+func (t *TestJob) renderChart(targetChart *chart.Chart, userValues []byte) (map[string]string, chartutil.Values, error) {
+	if t.helm3Chart != nil {
+		return t.renderHelm3Chart(userValues)
+	}
+
 	config := &chart.Config{Raw: string(userValues), Values: map[string]*chart.Value{}}
 	options := *t.releaseOption()
 	caps := *t.capabilityOption()
 
+	// process the requirements like `helm install`, on a copy to not affect the other tests
+	targetChart = copyChart(targetChart)
+	if err := t.overrideFilesOfChart(targetChart); err != nil {
+		return nil, nil, err
+	}
+	if overrides := t.templateOverrides(); overrides != nil {
+		targetChart.Templates = withTemplateOverrides(targetChart.Templates, overrides)
+	}
+	if err := t.injectNamedTemplate(targetChart); err != nil {
+		return nil, nil, err
+	}
+	if err := chartutil.ProcessRequirementsEnabled(targetChart, config); err != nil {
+		return nil, nil, err
+	}
+	if err := chartutil.ProcessRequirementsImportValues(targetChart); err != nil {
+		return nil, nil, err
+	}
+
 	vals, err := chartutil.ToRenderValuesCaps(targetChart, config, options, &caps)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	values, _ := vals["Values"].(chartutil.Values)
+	violations, err := valuesSchemaViolationsOfChart(targetChart, values)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(violations) > 0 {
+		return nil, vals, &valuesSchemaError{violations}
 	}
 
 	renderer := engine.New()
+	for name, function := range t.funcMap() {
+		renderer.FuncMap[name] = function
+	}
 	outputOfFiles, err := renderer.Render(targetChart, vals)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	return outputOfFiles, vals, nil
+}
+
+// renderNothingForDisabledDependencies makes the templates of dependencies disabled by
+// requirements rendered as empty, so that the assertions of them see no documents
+func renderNothingForDisabledDependencies(
+	targetChart *chart.Chart,
+	helm3Chart *v3chart.Chart,
+	outputOfFiles map[string]string,
+) {
+	for _, name := range templateNamesOfChart(targetChart, helm3Chart, targetChart.Metadata.Name) {
+		if _, rendered := outputOfFiles[name]; !rendered && path.Ext(name) == ".yaml" {
+			outputOfFiles[name] = ""
+		}
+	}
+}
+
+// templateNamesOfChart returns the names of templates in chart and its dependencies (once per alias),
+// in the form of the keys of output rendered like "parent/charts/child/templates/a.yaml"
+func templateNamesOfChart(targetChart *chart.Chart, helm3Chart *v3chart.Chart, route string) []string {
+	names := make([]string, 0, len(targetChart.Templates))
+	for _, template := range targetChart.Templates {
+		names = append(names, path.Join(route, template.Name))
 	}
 
-	return outputOfFiles, nil
+	aliases := aliasesOfDependencies(targetChart, helm3Chart)
+	for _, dependency := range targetChart.Dependencies {
+		name := dependency.Metadata.Name
+		for _, alias := range routeNamesOfDependency(aliases, name) {
+			names = append(names, templateNamesOfChart(
+				dependency,
+				helm3DependencyOf(helm3Chart, name),
+				path.Join(route, "charts", alias),
+			)...)
+		}
+	}
+	return names
+}
+
+// copyChart copy the chart and its dependencies, since the dependencies and values of them
+// are replaced by chartutil.ProcessRequirementsEnabled and ProcessRequirementsImportValues
+func copyChart(targetChart *chart.Chart) *chart.Chart {
+	copiedChart := *targetChart
+	copiedChart.Dependencies = make([]*chart.Chart, len(targetChart.Dependencies))
+	for idx, dependency := range targetChart.Dependencies {
+		copiedChart.Dependencies[idx] = copyChart(dependency)
+	}
+	return &copiedChart
+}
+
+// releaseTime returns the release time frozen in test, or a fixed time if deterministic, otherwise now
+func (t *TestJob) releaseTime() (time.Time, error) {
+	if t.Release.Time != "" {
+		releaseTime, err := time.Parse(time.RFC3339, t.Release.Time)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid release.time %s, should be like 2020-01-01T00:00:00Z", t.Release.Time)
+		}
+		return releaseTime, nil
+	}
+	if t.deterministic {
+		return deterministicReleaseTime, nil
+	}
+	return time.Now(), nil
+}
+
+// funcMap returns the template functions to override the ones of engine with,
+// `now` is frozen if the release time is, and the random functions are seeded if deterministic
+func (t *TestJob) funcMap() template.FuncMap {
+	funcMap := template.FuncMap{}
+	if t.Release.Time != "" || t.deterministic {
+		releaseTime, _ := t.releaseTime()
+		for name, function := range nowFuncMap(releaseTime) {
+			funcMap[name] = function
+		}
+	}
+	if t.deterministic {
+		for name, function := range deterministicFuncMap() {
+			funcMap[name] = function
+		}
+	}
+	return funcMap
 }
 
 // get chartutil.ReleaseOptions ready for render
 func (t *TestJob) releaseOption() *chartutil.ReleaseOptions {
+	releaseTime, _ := t.releaseTime()
 	options := chartutil.ReleaseOptions{
 		Name:      "RELEASE-NAME",
 		Namespace: "NAMESPACE",
-		Time:      timeconv.Now(),
+		Time:      timeconv.Timestamp(releaseTime),
 		Revision:  t.Release.Revision,
-		IsInstall: !t.Release.IsUpgrade,
+		IsInstall: !t.Release.IsUpgrade && !t.Release.IsRollback,
 		IsUpgrade: t.Release.IsUpgrade,
 	}
 	if t.Release.Name != "" {
@@ -167,24 +519,261 @@ func (t *TestJob) releaseOption() *chartutil.ReleaseOptions {
 	return &options
 }
 
+// get the release object like `{{ .Release }}` in templates,
+// without the Time since it's not comparable
+func (t *TestJob) releaseOfRendered() map[string]interface{} {
+	options := t.releaseOption()
+	return map[string]interface{}{
+		"Name":       options.Name,
+		"Namespace":  options.Namespace,
+		"Revision":   options.Revision,
+		"IsInstall":  options.IsInstall,
+		"IsUpgrade":  options.IsUpgrade,
+		"IsRollback": t.Release.IsRollback,
+	}
+}
+
+// CapabilitiesConfig the capabilities shared by tests of suite
+type CapabilitiesConfig struct {
+	// the full kube version like "v1.27.3"
+	KubeVersion string `yaml:"kubeVersion"`
+	// the api versions like "apps/v1", or resource-level ones like "apps/v1/Deployment"
+	APIVersions []string `yaml:"apiVersions"`
+	// the file of api versions dumped by `kubectl api-versions`, relative to the suite file
+	APIVersionsFile string `yaml:"apiVersionsFile"`
+}
+
+// resolveCapabilities resolves the kube version and api versions of the first ones given in
+// capabilities of test, capabilities of suite and cli
+func (t *TestJob) resolveCapabilities() error {
+	suiteCapabilities := t.suiteCapabilities
+	if suiteCapabilities == nil {
+		suiteCapabilities = &CapabilitiesConfig{}
+	}
+
+	t.resolvedKubeVersion = ""
+	for _, kubeVersion := range []string{t.Capabilities.KubeVersion, suiteCapabilities.KubeVersion, t.defaultKubeVersion} {
+		if kubeVersion == "" {
+			continue
+		}
+		gitVersion, err := common.GitVersionOfKubeVersion(kubeVersion)
+		if err != nil {
+			return err
+		}
+		t.resolvedKubeVersion = gitVersion
+		break
+	}
+
+	suiteDir := filepath.Dir(t.definitionFile)
+	t.resolvedAPIVersions = nil
+	for _, level := range []struct {
+		apiVersions     []string
+		apiVersionsFile string
+		// the file given in cli is read from disk, even if the suite is packaged in chart archive
+		readFile func(string) ([]byte, error)
+	}{
+		{t.Capabilities.APIVersions, relativeTo(suiteDir, t.Capabilities.APIVersionsFile), t.readFile},
+		{suiteCapabilities.APIVersions, relativeTo(suiteDir, suiteCapabilities.APIVersionsFile), t.readFile},
+		{nil, t.defaultAPIVersionsFile, ioutil.ReadFile},
+	} {
+		apiVersions := append([]string{}, level.apiVersions...)
+		if level.apiVersionsFile != "" {
+			content, err := level.readFile(level.apiVersionsFile)
+			if err != nil {
+				return err
+			}
+			apiVersions = append(apiVersions, parseAPIVersions(content)...)
+		}
+		if len(apiVersions) > 0 {
+			t.resolvedAPIVersions = expandAPIVersions(apiVersions)
+			break
+		}
+	}
+	return nil
+}
+
+// parseAPIVersions parses the api versions dumped by `kubectl api-versions`, one in each line
+func parseAPIVersions(content []byte) []string {
+	apiVersions := make([]string, 0)
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			apiVersions = append(apiVersions, line)
+		}
+	}
+	return apiVersions
+}
+
+// expandAPIVersions adds the group versions of resource-level api versions,
+// like "apps/v1" of "apps/v1/Deployment"
+func expandAPIVersions(apiVersions []string) []string {
+	expanded := make([]string, 0, len(apiVersions))
+	for _, apiVersion := range apiVersions {
+		expanded = append(expanded, apiVersion)
+		if idx := strings.LastIndex(apiVersion, "/"); idx > 0 {
+			if resource := apiVersion[idx+1:]; resource != "" && unicode.IsUpper(rune(resource[0])) {
+				expanded = append(expanded, apiVersion[:idx])
+			}
+		}
+	}
+	return expanded
+}
+
+// relativeTo returns the path joined to dir if it's relative and not empty
+func relativeTo(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
 // get chartutil.CapabilityOptions ready for render
-// Only supports APIVersions for now
+// with the kube version and api versions resolved
 func (t *TestJob) capabilityOption() *chartutil.Capabilities {
-	options := chartutil.Capabilities{APIVersions: chartutil.DefaultVersionSet, KubeVersion: chartutil.DefaultKubeVersion}
+	// copy the default one to not modify it
+	kubeVersion := *chartutil.DefaultKubeVersion
+	options := chartutil.Capabilities{APIVersions: chartutil.DefaultVersionSet, KubeVersion: &kubeVersion}
+	if major, minor, err := common.ParseKubeVersion(t.resolvedKubeVersion); err == nil {
+		options.KubeVersion.Major = strconv.Itoa(major)
+		options.KubeVersion.Minor = strconv.Itoa(minor)
+		options.KubeVersion.GitVersion = t.resolvedKubeVersion
+	}
 	if t.Capabilities.KubeVersionMajor != "" {
 		options.KubeVersion.Major = t.Capabilities.KubeVersionMajor
 	}
 	if t.Capabilities.KubeVersionMinor != "" {
 		options.KubeVersion.Minor = t.Capabilities.KubeVersionMinor
 	}
-	if len(t.Capabilities.APIVersions) > 0 {
-		var arr []string
-		arr = append(t.Capabilities.APIVersions, "v1")
-		options.APIVersions = chartutil.NewVersionSet(arr...)
+	if len(t.resolvedAPIVersions) > 0 {
+		options.APIVersions = chartutil.NewVersionSet(append(t.resolvedAPIVersions, "v1")...)
 	}
 	return &options
 }
 
+// get the kube version like "1.14" in capabilities
+func (t *TestJob) kubeVersion() string {
+	if t.helm3Chart != nil {
+		kubeVersion := t.helm3CapabilityOption().KubeVersion
+		return kubeVersion.Major + "." + kubeVersion.Minor
+	}
+	kubeVersion := t.capabilityOption().KubeVersion
+	return kubeVersion.Major + "." + kubeVersion.Minor
+}
+
+// get schema.Validator with the schema locations and kube version of the test,
+// the CRDs in `crds` directory of charts, rendered or specified are added
+func (t *TestJob) schemaValidator(
+	targetChart *chart.Chart,
+	manifestsOfFiles map[string][]common.K8sManifest,
+) (*schema.Validator, error) {
+	if t.crdSchemas == nil {
+		t.crdSchemas = &crdSchemaCache{}
+	}
+	crdValidator, err := t.crdSchemas.get(func() (*schema.Validator, error) {
+		return t.loadCRDSchemas(targetChart)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	locations := append([]string{}, t.schemaLocations...)
+	if defaultLocation := schema.DefaultLocation(); defaultLocation != "" {
+		locations = append(locations, defaultLocation)
+	}
+	// copied to not add the rendered CRDs to the ones shared by tests
+	validator := crdValidator.Copy()
+	validator.Locations = locations
+	validator.KubeVersion = t.kubeVersion()
+
+	for _, manifests := range manifestsOfFiles {
+		for _, manifest := range manifests {
+			if err := validator.AddCustomResourceDefinition(manifest); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return validator, nil
+}
+
+// crdSchemaCache the validator of the CRDs not rendered, loaded by the first test validating manifests
+type crdSchemaCache struct {
+	loaded    bool
+	validator *schema.Validator
+	err       error
+}
+
+func (c *crdSchemaCache) get(load func() (*schema.Validator, error)) (*schema.Validator, error) {
+	if !c.loaded {
+		c.validator, c.err = load()
+		c.loaded = true
+	}
+	return c.validator, c.err
+}
+
+// loadCRDSchemas returns the validator with the CRDs in `crds` directory of charts and crdFiles added
+func (t *TestJob) loadCRDSchemas(targetChart *chart.Chart) (*schema.Validator, error) {
+	validator := &schema.Validator{}
+	if err := addCRDsOfChart(validator, targetChart); err != nil {
+		return nil, err
+	}
+
+	for _, crdPath := range t.crdFiles {
+		files, err := crdFilesOfPath(crdPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			if err := validator.AddCustomResourceDefinitions(content); err != nil {
+				return nil, fmt.Errorf("failed to add CRDs in %s: %s", file, err)
+			}
+		}
+	}
+	return validator, nil
+}
+
+// add CRDs in `crds` directory of the chart and its dependencies to validator
+func addCRDsOfChart(validator *schema.Validator, targetChart *chart.Chart) error {
+	for _, file := range targetChart.Files {
+		if !strings.HasPrefix(file.TypeUrl, "crds/") {
+			continue
+		}
+		if err := validator.AddCustomResourceDefinitions(file.Value); err != nil {
+			return fmt.Errorf("failed to add CRDs in %s: %s", file.TypeUrl, err)
+		}
+	}
+	for _, dependency := range targetChart.Dependencies {
+		if err := addCRDsOfChart(validator, dependency); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the yaml or json files of CRDs in crdPath if it's a directory, or crdPath itself
+func crdFilesOfPath(crdPath string) ([]string, error) {
+	info, err := os.Stat(crdPath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{crdPath}, nil
+	}
+
+	files := make([]string, 0)
+	for _, pattern := range []string{"*.yaml", "*.yml", "*.json"} {
+		matched, err := filepath.Glob(filepath.Join(crdPath, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matched...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
 // parse rendered manifest if it's yaml
 func (t *TestJob) parseManifestsFromOutputOfFiles(outputOfFiles map[string]string) (
 	map[string][]common.K8sManifest,
@@ -223,12 +812,26 @@ func (t *TestJob) parseManifestsFromOutputOfFiles(outputOfFiles map[string]strin
 // run Assert of all assertions of test
 func (t *TestJob) runAssertions(
 	manifestsOfFiles map[string][]common.K8sManifest,
+	renderedValues chartutil.Values,
 	snapshotComparer validators.SnapshotComparer,
+	schemaValidator validators.SchemaValidator,
+	valuesSchemaViolations []string,
+	previousManifestsOfFiles map[string][]common.K8sManifest,
 ) (bool, []*AssertionResult) {
 	testPass := true
 	assertsResult := make([]*AssertionResult, len(t.Assertions))
 
+	release := t.releaseOfRendered()
+	values, _ := renderedValues["Values"].(chartutil.Values)
+
 	for idx, assertion := range t.Assertions {
+		assertion.release = release
+		assertion.values = values
+		assertion.schemaValidator = schemaValidator
+		assertion.kubeVersion = t.kubeVersion()
+		assertion.valuesSchemaViolations = valuesSchemaViolations
+		assertion.previousDocs = previousManifestsOfFiles
+
 		result := assertion.Assert(
 			manifestsOfFiles,
 			snapshotComparer,
@@ -241,6 +844,79 @@ func (t *TestJob) runAssertions(
 	return testPass, assertsResult
 }
 
+// validate manifests of each file with validator, one result for each file
+func (t *TestJob) validateManifestsOfFiles(
+	manifestsOfFiles map[string][]common.K8sManifest,
+	assertType string,
+	description string,
+	validator validators.Validatable,
+	context validators.ValidateContext,
+	startIndex int,
+) (bool, []*AssertionResult) {
+	files := make([]string, 0, len(manifestsOfFiles))
+	for file := range manifestsOfFiles {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	allPassed := true
+	results := make([]*AssertionResult, len(files))
+	for idx, file := range files {
+		context.Docs = manifestsOfFiles[file]
+		passed, failInfo := validator.Validate(&context)
+		results[idx] = &AssertionResult{
+			Index:      startIndex + idx,
+			Passed:     passed,
+			FailInfo:   failInfo,
+			AssertType: assertType,
+			CustomInfo: fmt.Sprintf("- %s of `%s` fail", description, file),
+		}
+		allPassed = allPassed && passed
+	}
+	return allPassed, results
+}
+
+// checkBestPractices returns the result of best practice violations reported as failures,
+// and the result of the ones reported as warnings, which is passed
+func (t *TestJob) checkBestPractices(
+	manifestsOfFiles map[string][]common.K8sManifest,
+	startIndex int,
+) (bool, []*AssertionResult, error) {
+	failures, warnings, err := t.bestPractices.Check(manifestsOfFiles)
+	if err != nil {
+		return false, nil, err
+	}
+
+	results := make([]*AssertionResult, 0, 2)
+	if len(failures) > 0 {
+		results = append(results, &AssertionResult{
+			Index:      startIndex,
+			FailInfo:   append([]string{"Violations:"}, indentLines(failures)...),
+			AssertType: "bestPractices",
+			CustomInfo: "- best practices check fail",
+		})
+	}
+	if len(warnings) > 0 {
+		results = append(results, &AssertionResult{
+			Index:      startIndex + len(results),
+			Passed:     true,
+			Warning:    true,
+			FailInfo:   append([]string{"Violations:"}, indentLines(warnings)...),
+			AssertType: "bestPractices",
+			CustomInfo: "- best practices check warning",
+		})
+	}
+	return len(failures) == 0, results, nil
+}
+
+func indentLines(lines []string) []string {
+	indented := make([]string, len(lines))
+	for idx, line := range lines {
+		indented[idx] = "\t" + line
+	}
+	return indented
+}
+
 // add prefix to Assertion.Template
 func (t *TestJob) polishAssertionsTemplate(targetChart *chart.Chart) {
 	if t.chartRoute == "" {
@@ -248,13 +924,18 @@ func (t *TestJob) polishAssertionsTemplate(targetChart *chart.Chart) {
 	}
 
 	for _, assertion := range t.Assertions {
+		assertion.policies = t.policies
+
 		var templateToAssert string
 
 		if assertion.Template == "" {
-			if t.defaultTemplateToAssert == "" {
-				return
+			if t.NamedTemplate != nil {
+				templateToAssert = namedTemplateFile
+			} else if t.defaultTemplateToAssert == "" {
+				continue
+			} else {
+				templateToAssert = t.defaultTemplateToAssert
 			}
-			templateToAssert = t.defaultTemplateToAssert
 		} else {
 			templateToAssert = assertion.Template
 		}
@@ -11,6 +11,7 @@ type TestJobResult struct {
 
 func (tjr TestJobResult) print(printer *Printer, verbosity int) {
 	if tjr.Passed {
+		tjr.printWarnings(printer, verbosity)
 		return
 	}
 
@@ -29,3 +30,17 @@ func (tjr TestJobResult) print(printer *Printer, verbosity int) {
 		assertResult.print(printer, verbosity)
 	}
 }
+
+func (tjr TestJobResult) printWarnings(printer *Printer, verbosity int) {
+	warned := false
+	for _, assertResult := range tjr.AssertsResult {
+		if !assertResult.Warning {
+			continue
+		}
+		if !warned {
+			printer.println(printer.warning("- "+tjr.DisplayName+"\n"), 1)
+			warned = true
+		}
+		assertResult.print(printer, verbosity)
+	}
+}
@@ -3,15 +3,18 @@ package unittest_test
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/bradleyjkemp/cupaloy"
+	"github.com/golang/protobuf/ptypes/any"
 	. "github.com/lrills/helm-unittest/unittest"
 	"github.com/lrills/helm-unittest/unittest/snapshot"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
 
 	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
 )
 
 func TestUnmarshalableJobFromYAML(t *testing.T) {
@@ -195,3 +198,457 @@ asserts:
 	a.True(testResult.Passed)
 	a.Equal(1, len(testResult.AssertsResult))
 }
+
+var valuesSchemaToTestJob = `{
+  "type": "object",
+  "properties": {
+    "replicaCount": {"type": "integer", "minimum": 1}
+  }
+}`
+
+func TestRunJobWithValuesSchemaViolated(t *testing.T) {
+	c, _ := chartutil.Load("../__fixtures__/basic")
+	c.Files = append(c.Files, &any.Any{TypeUrl: "values.schema.json", Value: []byte(valuesSchemaToTestJob)})
+	manifest := `
+it: should fail
+set:
+  replicaCount: 0
+asserts:
+  - equal:
+      path: spec.replicas
+      value: 0
+    template: deployment.yaml
+`
+	var tj TestJob
+	yaml.Unmarshal([]byte(manifest), &tj)
+
+	testResult := tj.Run(c, &snapshot.Cache{}, &TestJobResult{})
+
+	a := assert.New(t)
+	a.EqualError(
+		testResult.ExecError,
+		"values don't meet the values.schema.json of charts:\n\tbasic/replicaCount: Must be greater than or equal to 1",
+	)
+	a.False(testResult.Passed)
+}
+
+func TestRunJobWithFailedValuesSchemaAssertion(t *testing.T) {
+	c, _ := chartutil.Load("../__fixtures__/basic")
+	c.Files = append(c.Files, &any.Any{TypeUrl: "values.schema.json", Value: []byte(valuesSchemaToTestJob)})
+	manifest := `
+it: should violate schema
+set:
+  replicaCount: one
+asserts:
+  - failedValuesSchema:
+      errorPattern: "replicaCount: Invalid type"
+`
+	var tj TestJob
+	yaml.Unmarshal([]byte(manifest), &tj)
+
+	testResult := tj.Run(c, &snapshot.Cache{}, &TestJobResult{})
+
+	a := assert.New(t)
+	a.Nil(testResult.ExecError)
+	a.True(testResult.Passed)
+	a.Equal(1, len(testResult.AssertsResult))
+}
+
+func TestRunJobWithInvalidReleaseTime(t *testing.T) {
+	c, _ := chartutil.Load("../__fixtures__/basic")
+	manifest := `
+it: should error
+release:
+  time: yesterday
+asserts:
+  - isKind:
+      of: Deployment
+    template: deployment.yaml
+`
+	var tj TestJob
+	yaml.Unmarshal([]byte(manifest), &tj)
+
+	testResult := tj.Run(c, &snapshot.Cache{}, &TestJobResult{})
+
+	a := assert.New(t)
+	a.EqualError(testResult.ExecError, "invalid release.time yesterday, should be like 2020-01-01T00:00:00Z")
+	a.False(testResult.Passed)
+}
+
+func TestRunJobWithInvalidCRDOfChart(t *testing.T) {
+	c, _ := chartutil.Load("../__fixtures__/basic")
+	c.Files = append(c.Files, &any.Any{TypeUrl: "crds/invalid.yaml", Value: []byte(`
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: invalid
+spec:
+  names:
+    kind: Invalid
+`)})
+	manifest := `
+it: should not load CRDs without validating manifests
+asserts:
+  - isKind:
+      of: Deployment
+    template: deployment.yaml
+`
+	var tj TestJob
+	yaml.Unmarshal([]byte(manifest), &tj)
+
+	testResult := tj.Run(c, &snapshot.Cache{}, &TestJobResult{})
+
+	a := assert.New(t)
+	a.Nil(testResult.ExecError)
+	a.True(testResult.Passed)
+
+	manifest = `
+it: should error with the invalid CRD
+asserts:
+  - isValidManifest: {}
+    template: deployment.yaml
+`
+	var validatingJob TestJob
+	yaml.Unmarshal([]byte(manifest), &validatingJob)
+
+	testResult = validatingJob.Run(c, &snapshot.Cache{}, &TestJobResult{})
+
+	a.EqualError(
+		testResult.ExecError,
+		"failed to add CRDs in crds/invalid.yaml: invalid CustomResourceDefinition, spec.group and spec.names.kind are required",
+	)
+}
+
+func TestRunJobWithLookupOfHelm2Chart(t *testing.T) {
+	c, _ := chartutil.Load("../__fixtures__/basic")
+	c.Templates = append(c.Templates, &chart.Template{
+		Name: "templates/lookup.yaml",
+		Data: []byte(`
+{{- $existing := lookup "v1" "ConfigMap" "NAMESPACE" "existing" }}
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: lookup
+`),
+	})
+	manifest := `
+it: should fail to render like helm 2
+kubernetesProvider:
+  objects:
+    - apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: existing
+        namespace: NAMESPACE
+asserts:
+  - isKind:
+      of: ConfigMap
+    template: lookup.yaml
+`
+	var tj TestJob
+	yaml.Unmarshal([]byte(manifest), &tj)
+
+	testResult := tj.Run(c, &snapshot.Cache{}, &TestJobResult{})
+
+	a := assert.New(t)
+	a.NotNil(testResult.ExecError)
+	a.Contains(testResult.ExecError.Error(), `function "lookup" not defined`)
+	a.False(testResult.Passed)
+}
+
+func TestRunJobWithCapabilities(t *testing.T) {
+	c, _ := chartutil.Load("../__fixtures__/basic")
+	c.Templates = append(c.Templates, &chart.Template{
+		Name: "templates/capabilities.yaml",
+		Data: []byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: capabilities
+data:
+  gitVersion: {{ .Capabilities.KubeVersion.GitVersion | quote }}
+  atLeast126: {{ semverCompare ">=1.26.0" .Capabilities.KubeVersion.GitVersion | quote }}
+  deployment: {{ .Capabilities.APIVersions.Has "apps/v1/Deployment" | quote }}
+  apps: {{ .Capabilities.APIVersions.Has "apps/v1" | quote }}
+  dumped: {{ .Capabilities.APIVersions.Has "monitoring.coreos.com/v1" | quote }}
+`),
+	})
+	manifest := `
+it: should render with the capabilities
+capabilities:
+  kubeVersion: v1.27.3
+  apiVersions:
+    - apps/v1/Deployment
+  apiVersionsFile: %s
+asserts:
+  - equal:
+      path: data.gitVersion
+      value: v1.27.3
+    template: capabilities.yaml
+  - equal:
+      path: data.atLeast126
+      value: "true"
+    template: capabilities.yaml
+  - equal:
+      path: data.deployment
+      value: "true"
+    template: capabilities.yaml
+  - equal:
+      path: data.apps
+      value: "true"
+    template: capabilities.yaml
+  - equal:
+      path: data.dumped
+      value: "true"
+    template: capabilities.yaml
+`
+	file, _ := ioutil.TempFile("", "testjob_test_TestRunJobWithCapabilities.txt")
+	defer os.Remove(file.Name())
+	file.WriteString("# kubectl api-versions\nmonitoring.coreos.com/v1\n\nv1\n")
+	file.Close()
+
+	var tj TestJob
+	yaml.Unmarshal([]byte(fmt.Sprintf(manifest, file.Name())), &tj)
+
+	testResult := tj.Run(c, &snapshot.Cache{}, &TestJobResult{})
+
+	a := assert.New(t)
+	a.Nil(testResult.ExecError)
+	a.True(testResult.Passed)
+	a.Equal(5, len(testResult.AssertsResult))
+}
+
+func TestRunJobWithInvalidKubeVersionInCapabilities(t *testing.T) {
+	c, _ := chartutil.Load("../__fixtures__/basic")
+	manifest := `
+it: should error
+capabilities:
+  kubeVersion: latest
+asserts:
+  - isKind:
+      of: Deployment
+    template: deployment.yaml
+`
+	var tj TestJob
+	yaml.Unmarshal([]byte(manifest), &tj)
+
+	testResult := tj.Run(c, &snapshot.Cache{}, &TestJobResult{})
+
+	a := assert.New(t)
+	a.NotNil(testResult.ExecError)
+	a.False(testResult.Passed)
+}
+
+func TestRunJobWithFilesOverridden(t *testing.T) {
+	c, _ := chartutil.Load("../__fixtures__/basic")
+	c.Files = append(c.Files, &any.Any{TypeUrl: "config/app.conf", Value: []byte("committed")})
+	c.Templates = append(c.Templates, &chart.Template{
+		Name: "templates/files.yaml",
+		Data: []byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: files
+data:
+  app: {{ .Files.Get "config/app.conf" | quote }}
+  count: {{ len (.Files.Glob "config/*") | quote }}
+  extra: {{ .Files.Get "config/extra.conf" | quote }}
+`),
+	})
+	manifest := `
+it: should read the files overridden
+files:
+  - name: config/app.conf
+    content: overridden
+  - name: config/extra.conf
+    path: %s
+asserts:
+  - equal:
+      path: data.app
+      value: overridden
+    template: files.yaml
+  - equal:
+      path: data.count
+      value: "2"
+    template: files.yaml
+  - equal:
+      path: data.extra
+      value: from file
+    template: files.yaml
+`
+	file, _ := ioutil.TempFile("", "testjob_test_TestRunJobWithFilesOverridden.conf")
+	defer os.Remove(file.Name())
+	file.WriteString("from file")
+	file.Close()
+
+	var tj TestJob
+	yaml.Unmarshal([]byte(fmt.Sprintf(manifest, file.Name())), &tj)
+
+	testResult := tj.Run(c, &snapshot.Cache{}, &TestJobResult{})
+
+	a := assert.New(t)
+	a.Nil(testResult.ExecError)
+	a.True(testResult.Passed)
+	a.Equal(3, len(testResult.AssertsResult))
+	a.Equal("committed", string(c.Files[len(c.Files)-1].Value))
+}
+
+func TestRunJobWithNamedTemplate(t *testing.T) {
+	c, _ := chartutil.Load("../__fixtures__/basic")
+	manifest := `
+it: should render the named template
+namedTemplate:
+  name: basic.fullname
+set:
+  nameOverride: john-doe
+asserts:
+  - equal:
+      path: output
+      value: RELEASE-NAME-john-doe
+`
+	var tj TestJob
+	yaml.Unmarshal([]byte(manifest), &tj)
+
+	testResult := tj.Run(c, &snapshot.Cache{}, &TestJobResult{})
+
+	a := assert.New(t)
+	a.Nil(testResult.ExecError)
+	a.True(testResult.Passed)
+	a.Equal(1, len(testResult.AssertsResult))
+}
+
+func TestRunJobWithNamedTemplateOfContext(t *testing.T) {
+	c, _ := chartutil.Load("../__fixtures__/basic")
+	c.Templates = append(c.Templates, &chart.Template{
+		Name: "templates/_labels.tpl",
+		Data: []byte(`
+{{- define "basic.labels" -}}
+app: {{ .Chart.Name }}
+release: {{ .Release.Name }}
+{{- end -}}
+`),
+	})
+	manifest := `
+it: should render the named template with context
+namedTemplate:
+  name: basic.labels
+  context:
+    Chart:
+      Name: my-chart
+    Release:
+      Name: my-release
+asserts:
+  - equal:
+      path: app
+      value: my-chart
+  - equal:
+      path: release
+      value: my-release
+`
+	var tj TestJob
+	yaml.Unmarshal([]byte(manifest), &tj)
+
+	testResult := tj.Run(c, &snapshot.Cache{}, &TestJobResult{})
+
+	a := assert.New(t)
+	a.Nil(testResult.ExecError)
+	a.True(testResult.Passed)
+	a.Equal(2, len(testResult.AssertsResult))
+}
+
+func TestRunJobWithHookAssertions(t *testing.T) {
+	c, _ := chartutil.Load("../__fixtures__/basic")
+	c.Templates = append(c.Templates, &chart.Template{
+		Name: "templates/hooks.yaml",
+		Data: []byte(`
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migration
+  annotations:
+    helm.sh/hook: pre-install,pre-upgrade
+    helm.sh/hook-weight: "-5"
+    helm.sh/hook-delete-policy: before-hook-creation
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: notification
+  annotations:
+    helm.sh/hook: post-upgrade,post-rollback
+`),
+	})
+	manifest := `
+it: should run the migration before other resources on upgrade
+release:
+  isUpgrade: true
+asserts:
+  - hasDocuments:
+      count: 1
+    hook: pre-upgrade
+    template: hooks.yaml
+  - equal:
+      path: metadata.name
+      value: migration
+    hook: pre-upgrade
+    template: hooks.yaml
+  - runsBeforeResources: {}
+    hook: pre-upgrade
+    template: hooks.yaml
+  - isHook:
+      events: [pre-upgrade]
+      weight: -5
+      deletePolicies: [before-hook-creation]
+    template: hooks.yaml
+  - runsBeforeResources: {}
+    not: true
+    template: hooks.yaml
+    documentIndex: 1
+`
+	var tj TestJob
+	yaml.Unmarshal([]byte(manifest), &tj)
+
+	testResult := tj.Run(c, &snapshot.Cache{}, &TestJobResult{})
+
+	a := assert.New(t)
+	a.Nil(testResult.ExecError)
+	a.True(testResult.Passed)
+	a.Equal(5, len(testResult.AssertsResult))
+}
+
+func TestRunJobWithRollback(t *testing.T) {
+	c, _ := chartutil.Load("../__fixtures__/basic")
+	c.Templates = append(c.Templates, &chart.Template{
+		Name: "templates/hooks.yaml",
+		Data: []byte(`
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migration
+  annotations:
+    helm.sh/hook: pre-install,pre-upgrade
+`),
+	})
+	manifest := `
+it: should not run the migration on rollback
+release:
+  isRollback: true
+asserts:
+  - runsBeforeResources: {}
+    not: true
+    template: hooks.yaml
+  - hasDocuments:
+      count: 0
+    hook: pre-rollback
+    template: hooks.yaml
+`
+	var tj TestJob
+	yaml.Unmarshal([]byte(manifest), &tj)
+
+	testResult := tj.Run(c, &snapshot.Cache{}, &TestJobResult{})
+
+	a := assert.New(t)
+	a.Nil(testResult.ExecError)
+	a.True(testResult.Passed)
+	a.Equal(2, len(testResult.AssertsResult))
+}
@@ -2,14 +2,22 @@ package unittest
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/lrills/helm-unittest/unittest/snapshot"
+	v3chart "helm.sh/helm/v3/pkg/chart"
+	v3loader "helm.sh/helm/v3/pkg/chart/loader"
 	"k8s.io/helm/pkg/chartutil"
 	"k8s.io/helm/pkg/proto/hapi/chart"
 )
 
+// recursivePathSuffix the suffix of chart path like "./charts/..." to run all charts under the directory
+const recursivePathSuffix = "..."
+
 // testUnitCounting stores counting numbers of test unit status
 type testUnitCounting struct {
 	passed  uint
@@ -34,6 +42,15 @@ func (counting testUnitCounting) sprint(printer *Printer) string {
 	)
 }
 
+// minus returns the counting since the previous one
+func (counting testUnitCounting) minus(previous testUnitCounting) testUnitCounting {
+	return testUnitCounting{
+		passed:  counting.passed - previous.passed,
+		failed:  counting.failed - previous.failed,
+		errored: counting.errored - previous.errored,
+	}
+}
+
 // testUnitCountingWithSnapshotFailed store testUnitCounting with snapshotFailed field
 type testUnitCountingWithSnapshotFailed struct {
 	testUnitCounting
@@ -57,11 +74,24 @@ type TestRunner struct {
 	snapshotCounting totalSnapshotCounting
 }
 
-// Run test suites in chart in ChartPaths
+// Run test suites in chart in ChartPaths, the paths like "./charts/..." are expanded to
+// all the charts under the directory
 func (tr *TestRunner) Run(ChartPaths []string) bool {
 	allPassed := true
 	start := time.Now()
-	for _, chartPath := range ChartPaths {
+	chartPaths, errs := tr.discoverChartPaths(ChartPaths)
+	for _, err := range errs {
+		tr.printErroredChartHeader(err)
+		tr.countChart(false, err)
+		allPassed = false
+	}
+	suitesInTestsDir, err := tr.getTestSuitesInTestsDir()
+	if err != nil {
+		tr.printErroredChartHeader(err)
+		tr.countChart(false, err)
+		allPassed = false
+	}
+	for _, chartPath := range chartPaths {
 		chart, err := chartutil.Load(chartPath)
 		if err != nil {
 			tr.printErroredChartHeader(err)
@@ -70,7 +100,17 @@ func (tr *TestRunner) Run(ChartPaths []string) bool {
 			continue
 		}
 
-		testSuites, err := tr.getTestSuites(chartPath, chart.Metadata.Name, chart)
+		var helm3Chart *v3chart.Chart
+		if usesHelm3(tr.Config.HelmVersion, chart) {
+			if helm3Chart, err = v3loader.Load(chartPath); err != nil {
+				tr.printErroredChartHeader(err)
+				tr.countChart(false, err)
+				allPassed = false
+				continue
+			}
+		}
+
+		previous, err := tr.loadPreviousChart(chartPath, chart, helm3Chart != nil)
 		if err != nil {
 			tr.printErroredChartHeader(err)
 			tr.countChart(false, err)
@@ -78,19 +118,114 @@ func (tr *TestRunner) Run(ChartPaths []string) bool {
 			continue
 		}
 
+		testSuites, err := tr.getTestSuites(chartPath, chart.Metadata.Name, chart, helm3Chart)
+		if err != nil {
+			tr.printErroredChartHeader(err)
+			tr.countChart(false, err)
+			allPassed = false
+			continue
+		}
+
+		testSuites = append(testSuites, tr.routeTestSuites(
+			suitesInTestsDir[chart.Metadata.Name], chart, helm3Chart,
+		)...)
+		delete(suitesInTestsDir, chart.Metadata.Name)
+
 		tr.printChartHeader(chart, chartPath)
-		chartPassed := tr.runSuitesOfChart(testSuites, chart)
+		if tr.Config.Deterministic && helm3Chart != nil {
+			tr.Printer.println(tr.Printer.warning(
+				"--deterministic is not applied to the chart rendered with helm 3, "+
+					"its random functions and `now` render as usual",
+			), 0)
+		}
+		suiteCounting, testCounting := tr.suiteCounting.testUnitCounting, tr.testCounting
+		chartPassed := tr.runSuitesOfChart(testSuites, chart, helm3Chart, previous, tr.snapshotDirOf(chartPath))
+		if len(chartPaths) > 1 {
+			tr.printChartSummary(
+				chart,
+				tr.suiteCounting.testUnitCounting.minus(suiteCounting),
+				tr.testCounting.minus(testCounting),
+			)
+		}
 
 		tr.countChart(chartPassed, nil)
 		allPassed = allPassed && chartPassed
 	}
+	untestedCharts := make([]string, 0, len(suitesInTestsDir))
+	for name := range suitesInTestsDir {
+		untestedCharts = append(untestedCharts, name)
+	}
+	sort.Strings(untestedCharts)
+	for _, name := range untestedCharts {
+		for _, suite := range suitesInTestsDir[name] {
+			tr.handleSuiteResult(&TestSuiteResult{
+				FilePath:  suite.definitionFile,
+				ExecError: fmt.Errorf("chart %s of the suite is not tested", name),
+			})
+			allPassed = false
+		}
+	}
 	tr.printSnapshotSummary()
 	tr.printSummary(time.Now().Sub(start))
 	return allPassed
 }
 
-// getTestSuites return test files of the chart which matched patterns
-func (tr *TestRunner) getTestSuites(chartPath, chartRoute string, chart *chart.Chart) ([]*TestSuite, error) {
+// discoverChartPaths expands the paths like "./charts/..." to the charts under the directory,
+// the subcharts in charts directory of another chart are skipped if the tests of subcharts are
+// included, so are the hidden directories and node_modules. The duplicated paths are also removed.
+// The errors of directories failed to walk are returned, with the charts found in the others
+func (tr *TestRunner) discoverChartPaths(paths []string) ([]string, []error) {
+	chartPaths := make([]string, 0, len(paths))
+	errs := make([]error, 0)
+	found := make(map[string]bool)
+	addChartPath := func(chartPath string) {
+		if !found[filepath.Clean(chartPath)] {
+			found[filepath.Clean(chartPath)] = true
+			chartPaths = append(chartPaths, chartPath)
+		}
+	}
+
+	for _, path := range paths {
+		if filepath.Base(path) != recursivePathSuffix {
+			addChartPath(path)
+			continue
+		}
+
+		root := filepath.Dir(path)
+		filepath.Walk(root, func(dir string, info os.FileInfo, err error) error {
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to discover charts in %s: %s", dir, err))
+				return nil
+			}
+			if !info.IsDir() {
+				return nil
+			}
+			if dir != root && (strings.HasPrefix(info.Name(), ".") || info.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			if tr.Config.WithSubChart && info.Name() == "charts" && found[filepath.Dir(dir)] {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(dir, chartutil.ChartfileName)); err == nil {
+				addChartPath(dir)
+			}
+			return nil
+		})
+	}
+	return chartPaths, errs
+}
+
+// getTestSuites return test files of the chart which matched patterns, or the ones packaged if the
+// chart is an archive. The test files of subchart are returned once per alias of it
+func (tr *TestRunner) getTestSuites(
+	chartPath, chartRoute string,
+	chart *chart.Chart,
+	helm3Chart *v3chart.Chart,
+) ([]*TestSuite, error) {
+	if info, err := os.Stat(chartPath); err != nil || !info.IsDir() {
+		return tr.getArchivedTestSuites(chartPath, chartRoute, chart, helm3Chart)
+	}
+
 	filesSet := map[string]bool{}
 	for _, pattern := range tr.Config.TestFiles {
 		files, err := filepath.Glob(filepath.Join(chartPath, pattern))
@@ -105,38 +240,200 @@ func (tr *TestRunner) getTestSuites(chartPath, chartRoute string, chart *chart.C
 	resultSuites := make([]*TestSuite, 0, len(filesSet))
 	for file := range filesSet {
 		suite, err := ParseTestSuiteFile(file, chartRoute)
-		if err != nil {
+		resultSuites = tr.appendSuite(resultSuites, file, suite, err)
+	}
+
+	return tr.appendSubchartSuites(resultSuites, chartPath, chartRoute, chart, helm3Chart)
+}
+
+// getTestSuitesInTestsDir return test files in the tests dir given in cli, which matched the file
+// name of patterns, grouped by the name of root chart they tested
+func (tr *TestRunner) getTestSuitesInTestsDir() (map[string][]*TestSuite, error) {
+	suitesOfCharts := make(map[string][]*TestSuite)
+	if tr.Config.TestsDir == "" {
+		return suitesOfCharts, nil
+	}
+
+	patterns := make([]string, len(tr.Config.TestFiles))
+	for idx, pattern := range tr.Config.TestFiles {
+		patterns[idx] = filepath.Base(pattern)
+	}
+
+	err := filepath.Walk(tr.Config.TestsDir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		if matched, err := matchesAnyPattern(patterns, info.Name()); err != nil || !matched {
+			return err
+		}
+
+		suite, err := ParseTestSuiteFile(file, "")
+		if err == nil {
+			suite.chartRoute, err = routeOfSuiteInTestsDir(suite, tr.Config.TestsDir, file)
+		}
+		for _, suite := range tr.appendSuite(nil, file, suite, err) {
+			name := spliteChartRoutes(suite.chartRoute)[0]
+			suitesOfCharts[name] = append(suitesOfCharts[name], suite)
+		}
+		return nil
+	})
+	return suitesOfCharts, err
+}
+
+// routeOfSuiteInTestsDir returns the route of chart the suite in tests dir tests, which is given
+// in chart of the suite, or the name of directory in tests dir the suite placed in
+func routeOfSuiteInTestsDir(suite *TestSuite, testsDir, file string) (string, error) {
+	if suite.Chart != "" {
+		return filepath.Clean(filepath.FromSlash(suite.Chart)), nil
+	}
+
+	relativePath, err := filepath.Rel(testsDir, file)
+	if err != nil {
+		return "", err
+	}
+	if dir := filepath.Dir(relativePath); dir != "." {
+		return strings.Split(dir, string(filepath.Separator))[0], nil
+	}
+	return "", fmt.Errorf("chart of the suite should be given if it's not in a directory named by chart")
+}
+
+// routeTestSuites check the charts of suites in tests dir exist in the chart,
+// the suites of route not found are printed with error
+func (tr *TestRunner) routeTestSuites(
+	suites []*TestSuite,
+	chart *chart.Chart,
+	helm3Chart *v3chart.Chart,
+) []*TestSuite {
+	routedSuites := make([]*TestSuite, 0, len(suites))
+	for _, suite := range suites {
+		if !routeExists(chart, helm3Chart, spliteChartRoutes(suite.chartRoute)[1:]) {
 			tr.handleSuiteResult(&TestSuiteResult{
-				FilePath:  file,
-				ExecError: err,
+				FilePath:  suite.definitionFile,
+				ExecError: fmt.Errorf("chart %s of the suite not found", filepath.ToSlash(suite.chartRoute)),
 			})
 			continue
 		}
-		resultSuites = append(resultSuites, suite)
+		routedSuites = append(routedSuites, suite)
 	}
+	return routedSuites
+}
 
+// getArchivedTestSuites return test files packaged in the chart archive which matched patterns
+func (tr *TestRunner) getArchivedTestSuites(
+	chartPath, chartRoute string,
+	chart *chart.Chart,
+	helm3Chart *v3chart.Chart,
+) ([]*TestSuite, error) {
+	archivedFiles := make(map[string][]byte, len(chart.Files))
+	for _, file := range chart.Files {
+		archivedFiles[file.TypeUrl] = file.Value
+	}
+
+	resultSuites := make([]*TestSuite, 0)
+	for _, file := range chart.Files {
+		matched, err := matchesAnyPattern(tr.Config.TestFiles, file.TypeUrl)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		suite, err := parseArchivedTestSuite(chartPath, chartRoute, file.TypeUrl, archivedFiles)
+		resultSuites = tr.appendSuite(resultSuites, filepath.Join(chartPath, file.TypeUrl), suite, err)
+	}
+
+	return tr.appendSubchartSuites(resultSuites, chartPath, chartRoute, chart, helm3Chart)
+}
+
+// matchesAnyPattern whether the file name matches any of glob patterns
+func matchesAnyPattern(patterns []string, name string) (bool, error) {
+	for _, pattern := range patterns {
+		matched, err := filepath.Match(filepath.Clean(pattern), filepath.FromSlash(name))
+		if err != nil || matched {
+			return matched, err
+		}
+	}
+	return false, nil
+}
+
+// appendSuite append the suite parsed with the settings in cli, or print the error of parsing
+func (tr *TestRunner) appendSuite(suites []*TestSuite, file string, suite *TestSuite, err error) []*TestSuite {
+	if err != nil {
+		tr.handleSuiteResult(&TestSuiteResult{
+			FilePath:  file,
+			ExecError: err,
+		})
+		return suites
+	}
+	suite.Policies = append(suite.Policies, tr.Config.Policies...)
+	suite.CRDs = append(suite.CRDs, tr.Config.CRDs...)
+	suite.schemaLocations = tr.Config.SchemaLocations
+	suite.validateSchema = tr.Config.ValidateSchema
+	suite.kubeVersion = tr.Config.KubeVersion
+	suite.apiVersionsFile = tr.Config.APIVersionsFile
+	suite.checkBestPractices = tr.Config.BestPractices
+	suite.deterministic = tr.Config.Deterministic
+	return append(suites, suite)
+}
+
+// appendSubchartSuites append test suites of subcharts if enabled, once per alias of subchart
+func (tr *TestRunner) appendSubchartSuites(
+	resultSuites []*TestSuite,
+	chartPath, chartRoute string,
+	chart *chart.Chart,
+	helm3Chart *v3chart.Chart,
+) ([]*TestSuite, error) {
 	if tr.Config.WithSubChart {
+		aliases := aliasesOfDependencies(chart, helm3Chart)
 		for _, subchart := range chart.Dependencies {
-			subchartSuites, err := tr.getTestSuites(
-				filepath.Join(chartPath, "charts", subchart.Metadata.Name),
-				filepath.Join(chartRoute, "charts", subchart.Metadata.Name),
-				subchart,
-			)
-			if err != nil {
-				continue
+			name := subchart.Metadata.Name
+			for _, alias := range routeNamesOfDependency(aliases, name) {
+				subchartSuites, err := tr.getTestSuites(
+					filepath.Join(chartPath, "charts", name),
+					filepath.Join(chartRoute, "charts", alias),
+					subchart,
+					helm3DependencyOf(helm3Chart, name),
+				)
+				if err != nil {
+					return nil, fmt.Errorf(
+						"failed to get test suites of subchart %s: %s", filepath.Join(chartRoute, "charts", alias), err,
+					)
+				}
+				if alias != name {
+					for _, suite := range subchartSuites {
+						suite.aliases = append([]string{alias}, suite.aliases...)
+					}
+				}
+				resultSuites = append(resultSuites, subchartSuites...)
 			}
-			resultSuites = append(resultSuites, subchartSuites...)
 		}
 	}
-
 	return resultSuites, nil
 }
 
+// snapshotDirOf returns the directory to cache the snapshots of suites packaged in chart archives,
+// default to the `__snapshot__` directory in the chart, or besides the chart if it's an archive
+func (tr *TestRunner) snapshotDirOf(chartPath string) string {
+	if tr.Config.SnapshotDir != "" {
+		return tr.Config.SnapshotDir
+	}
+	if info, err := os.Stat(chartPath); err == nil && info.IsDir() {
+		return filepath.Join(chartPath, snapshot.DirName)
+	}
+	return filepath.Join(filepath.Dir(chartPath), snapshot.DirName)
+}
+
 // runSuitesOfChart runs suite files of the chart and print output
-func (tr *TestRunner) runSuitesOfChart(suites []*TestSuite, chart *chart.Chart) bool {
+func (tr *TestRunner) runSuitesOfChart(
+	suites []*TestSuite,
+	chart *chart.Chart,
+	helm3Chart *v3chart.Chart,
+	previous *previousChart,
+	snapshotDir string,
+) bool {
 	chartPassed := true
 	for _, suite := range suites {
-		snapshotCache, err := snapshot.CreateSnapshotOfSuite(suite.definitionFile, tr.Config.UpdateSnapshot)
+		snapshotCache, err := suite.createSnapshot(snapshotDir, tr.Config.UpdateSnapshot)
 		if err != nil {
 			tr.handleSuiteResult(&TestSuiteResult{
 				FilePath:  suite.definitionFile,
@@ -145,6 +442,8 @@ func (tr *TestRunner) runSuitesOfChart(suites []*TestSuite, chart *chart.Chart)
 			continue
 		}
 
+		suite.helm3Chart = helm3Chart
+		suite.previous = previous
 		result := suite.Run(chart, snapshotCache, &TestSuiteResult{})
 		chartPassed = chartPassed && result.Passed
 		tr.handleSuiteResult(result)
@@ -187,6 +486,22 @@ Time:        %s
 
 }
 
+// printChartSummary print summary of a chart after its suite results, when multiple charts run
+func (tr *TestRunner) printChartSummary(chart *chart.Chart, suiteCounting, testCounting testUnitCounting) {
+	summaryFormat := `
+Summary of [ %s ]  Test Suites: %s  Tests: %s
+`
+	tr.Printer.println(
+		fmt.Sprintf(
+			summaryFormat,
+			tr.Printer.highlight(chart.Metadata.Name),
+			suiteCounting.sprint(tr.Printer),
+			testCounting.sprint(tr.Printer),
+		),
+		0,
+	)
+}
+
 // printChartHeader print header before suite result of a chart
 func (tr *TestRunner) printChartHeader(chart *chart.Chart, path string) {
 	headerFormat := `
@@ -2,6 +2,10 @@ package unittest_test
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
@@ -108,3 +112,366 @@ func TestRunnerWithTestsInSubchartButFlagFalse(t *testing.T) {
 	assert.True(t, passed)
 	cupaloy.SnapshotT(t, makeOutputSnapshotable(buffer.String())...)
 }
+
+func TestRunnerWithHelm3Chart(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	runner := TestRunner{
+		Printer: NewPrinter(buffer, nil),
+		Config: TestConfig{
+			TestFiles: []string{"tests/*_test.yaml"},
+		},
+	}
+	passed := runner.Run([]string{"../__fixtures__/v3-basic"})
+	assert.True(t, passed, buffer.String())
+}
+
+func TestRunnerWithRequirementsProcessed(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	runner := TestRunner{
+		Printer: NewPrinter(buffer, nil),
+		Config: TestConfig{
+			WithSubChart: true,
+			TestFiles:    []string{"tests/*_test.yaml"},
+		},
+	}
+	passed := runner.Run([]string{"../__fixtures__/with-requirements"})
+	assert.True(t, passed, buffer.String())
+	assert.Contains(t, buffer.String(), "test service [another-child]")
+	assert.Contains(t, buffer.String(), "Test Suites: 3 passed, 3 total")
+}
+
+func TestRunnerWithTestsInPackagedChart(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "test")
+	defer os.RemoveAll(dir)
+
+	buffer := new(bytes.Buffer)
+	runner := TestRunner{
+		Printer: NewPrinter(buffer, nil),
+		Config: TestConfig{
+			TestFiles:   []string{"tests/*_test.yaml"},
+			SnapshotDir: dir,
+		},
+	}
+	passed := runner.Run([]string{"../__fixtures__/packaged/basic-0.1.0.tgz"})
+
+	a := assert.New(t)
+	a.True(passed, buffer.String())
+	a.Contains(buffer.String(), "Test Suites: 3 passed, 3 total")
+	_, err := os.Stat(filepath.Join(dir, "basic", "tests", "deployment_test.yaml.snap"))
+	a.Nil(err)
+}
+
+func TestRunnerWithAPIVersionsFileOfTestsInPackagedChart(t *testing.T) {
+	file, err := ioutil.TempFile("", "api-versions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("v1\napps/v1\nextensions/v1beta1\n")
+	file.Close()
+	dir, _ := ioutil.TempDir("", "test")
+	defer os.RemoveAll(dir)
+
+	buffer := new(bytes.Buffer)
+	runner := TestRunner{
+		Printer: NewPrinter(buffer, nil),
+		Config: TestConfig{
+			TestFiles:       []string{"tests/*_test.yaml"},
+			SnapshotDir:     dir,
+			APIVersionsFile: file.Name(),
+		},
+	}
+	passed := runner.Run([]string{"../__fixtures__/packaged/basic-0.1.0.tgz"})
+
+	a := assert.New(t)
+	a.True(passed, buffer.String())
+	a.Contains(buffer.String(), "Test Suites: 3 passed, 3 total")
+}
+
+func TestRunnerWithPoliciesOfTestsInPackagedChart(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	runner := TestRunner{
+		Printer: NewPrinter(buffer, nil),
+		Config: TestConfig{
+			TestFiles: []string{"tests/*_test.yaml"},
+		},
+	}
+	runner.Run([]string{"../__fixtures__/packaged/with-policies-0.1.0.tgz"})
+
+	a := assert.New(t)
+	a.Contains(buffer.String(), "policies and crds are not supported in the test suite packaged in chart archive")
+	a.Contains(buffer.String(), "Test Suites: 1 failed, 1 errored, 1 passed, 2 total")
+}
+
+func TestRunnerWithChartsDiscovered(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	runner := TestRunner{
+		Printer: NewPrinter(buffer, nil),
+		Config: TestConfig{
+			WithSubChart: true,
+			TestFiles:    []string{"tests/*_test.yaml"},
+		},
+	}
+	passed := runner.Run([]string{"../__fixtures__/with-subchart/...", "../__fixtures__/with-subchart"})
+
+	a := assert.New(t)
+	a.True(passed, buffer.String())
+	a.Contains(buffer.String(), "Charts:      1 passed, 1 total")
+	a.NotContains(buffer.String(), "Summary of")
+}
+
+func TestRunnerWithChartsDiscoveredSkippingHiddenDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, chartDir := range []string{"basic", ".git/basic", "node_modules/basic"} {
+		copyDir(t, "../__fixtures__/basic", filepath.Join(dir, chartDir))
+	}
+
+	buffer := new(bytes.Buffer)
+	runner := TestRunner{
+		Printer: NewPrinter(buffer, nil),
+		Config: TestConfig{
+			TestFiles:   []string{"tests/*_test.yaml"},
+			SnapshotDir: filepath.Join(dir, "snapshots"),
+		},
+	}
+	passed := runner.Run([]string{filepath.Join(dir, "...")})
+
+	a := assert.New(t)
+	a.True(passed, buffer.String())
+	a.Contains(buffer.String(), "Charts:      1 passed, 1 total")
+}
+
+func TestRunnerWithChartsDiscoveredInDirNotExisted(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	runner := TestRunner{
+		Printer: NewPrinter(buffer, nil),
+		Config: TestConfig{
+			TestFiles: []string{"tests/*_test.yaml"},
+		},
+	}
+	passed := runner.Run([]string{"../__fixtures__/not-existed/...", "../__fixtures__/basic"})
+
+	a := assert.New(t)
+	a.False(passed, buffer.String())
+	a.Contains(buffer.String(), "failed to discover charts in ../__fixtures__/not-existed")
+	a.Contains(buffer.String(), "### Chart [ basic ]")
+}
+
+func TestRunnerWithChartsDiscoveredIncludingSubcharts(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	runner := TestRunner{
+		Printer: NewPrinter(buffer, nil),
+		Config: TestConfig{
+			WithSubChart: false,
+			TestFiles:    []string{"tests/*_test.yaml"},
+		},
+	}
+	passed := runner.Run([]string{"../__fixtures__/with-subchart/..."})
+
+	a := assert.New(t)
+	a.True(passed, buffer.String())
+	a.Contains(buffer.String(), "Charts:      2 passed, 2 total")
+	a.Contains(buffer.String(), "Summary of [ parent-chart ]")
+	a.Contains(buffer.String(), "Summary of [ child-chart ]")
+}
+
+func TestRunnerWithTestsDir(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	runner := TestRunner{
+		Printer: NewPrinter(buffer, nil),
+		Config: TestConfig{
+			TestFiles: []string{"tests/*_test.yaml"},
+			TestsDir:  "../__fixtures__/tests-outside",
+		},
+	}
+	passed := runner.Run([]string{"../__fixtures__/basic", "../__fixtures__/with-subchart"})
+
+	a := assert.New(t)
+	a.False(passed)
+	a.Contains(buffer.String(), "test service outside the chart")
+	a.Contains(buffer.String(), "test service of subchart outside the chart")
+	a.Contains(buffer.String(), "chart not-tested-chart of the suite is not tested")
+	a.Contains(buffer.String(), "Test Suites: 1 failed, 1 errored, 8 passed, 9 total")
+}
+
+func TestRunnerWithDeterministicRendering(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	runner := TestRunner{
+		Printer: NewPrinter(buffer, nil),
+		Config: TestConfig{
+			TestFiles:     []string{"tests/*_test.yaml"},
+			Deterministic: true,
+		},
+	}
+	passed := runner.Run([]string{"../__fixtures__/with-random"})
+	assert.True(t, passed, buffer.String())
+}
+
+func TestRunnerWithDeterministicRenderingOfHelm3Chart(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	runner := TestRunner{
+		Printer: NewPrinter(buffer, nil),
+		Config: TestConfig{
+			TestFiles:     []string{"tests/*_test.yaml"},
+			Deterministic: true,
+		},
+	}
+	passed := runner.Run([]string{"../__fixtures__/v3-basic"})
+
+	a := assert.New(t)
+	a.True(passed, buffer.String())
+	a.Contains(buffer.String(), "--deterministic is not applied to the chart rendered with helm 3")
+}
+
+func TestRunnerWithUpgradeFromUnsafe(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	runner := TestRunner{
+		Printer: NewPrinter(buffer, nil),
+		Config: TestConfig{
+			TestFiles:   []string{"tests/*_test.yaml"},
+			UpgradeFrom: "../__fixtures__/with-upgrade/previous",
+		},
+	}
+	passed := runner.Run([]string{"../__fixtures__/with-upgrade/current"})
+
+	a := assert.New(t)
+	a.False(passed, buffer.String())
+	a.Contains(buffer.String(), "upgrade safety check against ../__fixtures__/with-upgrade/previous fail")
+	a.Contains(buffer.String(), "Deployment/RELEASE-NAME-web spec.selector changed")
+	// the values set are not validated with the values.schema.json of previous chart
+	a.NotContains(buffer.String(), "failed to render the chart to upgrade from")
+}
+
+func TestRunnerWithUpgradeFromSafe(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	runner := TestRunner{
+		Printer: NewPrinter(buffer, nil),
+		Config: TestConfig{
+			TestFiles:   []string{"tests/*_test.yaml"},
+			UpgradeFrom: "../__fixtures__/with-upgrade/current",
+		},
+	}
+	passed := runner.Run([]string{"../__fixtures__/with-upgrade/current"})
+	assert.True(t, passed, buffer.String())
+}
+
+func TestRunnerWithUpgradeFromAnotherChart(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	runner := TestRunner{
+		Printer: NewPrinter(buffer, nil),
+		Config: TestConfig{
+			TestFiles:   []string{"tests/*_test.yaml"},
+			UpgradeFrom: "../__fixtures__/basic",
+		},
+	}
+	passed := runner.Run([]string{"../__fixtures__/with-upgrade/current"})
+
+	a := assert.New(t)
+	a.False(passed)
+	a.Contains(buffer.String(), "the chart to upgrade from ../__fixtures__/basic is basic, not with-upgrade")
+}
+
+// commitChartToGit commits the files of chart to the path of a new git repository
+func commitChartToGit(t *testing.T, repository, chartPath, chart string) {
+	copyDir(t, chart, filepath.Join(repository, chartPath))
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+	} {
+		command := exec.Command("git", args...)
+		command.Dir = repository
+		if output, err := command.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %s %s", strings.Join(args, " "), err, output)
+		}
+	}
+}
+
+func copyDir(t *testing.T, src, dst string) {
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, relativePath)
+		if info.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(target, content, info.Mode())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunnerWithUpgradeFromGitRefOfChartAtRoot(t *testing.T) {
+	repository, _ := ioutil.TempDir("", "test")
+	defer os.RemoveAll(repository)
+	commitChartToGit(t, repository, ".", "../__fixtures__/with-upgrade/previous")
+	os.Remove(filepath.Join(repository, "values.schema.json"))
+	copyDir(t, "../__fixtures__/with-upgrade/current", repository)
+
+	buffer := new(bytes.Buffer)
+	runner := TestRunner{
+		Printer: NewPrinter(buffer, nil),
+		Config: TestConfig{
+			TestFiles:   []string{"tests/*_test.yaml"},
+			UpgradeFrom: "git:HEAD",
+		},
+	}
+	passed := runner.Run([]string{repository})
+
+	a := assert.New(t)
+	a.False(passed, buffer.String())
+	a.Contains(buffer.String(), "upgrade safety check against git:HEAD fail")
+	a.Contains(buffer.String(), "Deployment/RELEASE-NAME-web spec.selector changed")
+}
+
+func TestRunnerWithUpgradeFromGitRefWithoutChart(t *testing.T) {
+	repository, _ := ioutil.TempDir("", "test")
+	defer os.RemoveAll(repository)
+	commitChartToGit(t, repository, "charts/previous", "../__fixtures__/with-upgrade/previous")
+	copyDir(t, "../__fixtures__/with-upgrade/current", filepath.Join(repository, "charts", "current"))
+
+	buffer := new(bytes.Buffer)
+	runner := TestRunner{
+		Printer: NewPrinter(buffer, nil),
+		Config: TestConfig{
+			TestFiles:   []string{"tests/*_test.yaml"},
+			UpgradeFrom: "git:HEAD",
+		},
+	}
+	passed := runner.Run([]string{filepath.Join(repository, "charts", "current")})
+
+	a := assert.New(t)
+	a.True(passed, buffer.String())
+	a.NotContains(buffer.String(), "upgrade safety check")
+}
+
+func TestRunnerWithUpgradeFromUnknownGitRef(t *testing.T) {
+	repository, _ := ioutil.TempDir("", "test")
+	defer os.RemoveAll(repository)
+	commitChartToGit(t, repository, ".", "../__fixtures__/with-upgrade/current")
+
+	buffer := new(bytes.Buffer)
+	runner := TestRunner{
+		Printer: NewPrinter(buffer, nil),
+		Config: TestConfig{
+			TestFiles:   []string{"tests/*_test.yaml"},
+			UpgradeFrom: "git:no-such-branch",
+		},
+	}
+	passed := runner.Run([]string{repository})
+
+	a := assert.New(t)
+	a.False(passed)
+	a.Contains(buffer.String(), "no-such-branch is not a commit of the git repository")
+}
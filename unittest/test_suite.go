@@ -9,7 +9,9 @@ import (
 	"strings"
 
 	"github.com/lrills/helm-unittest/unittest/snapshot"
+	"github.com/lrills/helm-unittest/unittest/validators"
 	"gopkg.in/yaml.v2"
+	v3chart "helm.sh/helm/v3/pkg/chart"
 	"k8s.io/helm/pkg/proto/hapi/chart"
 )
 
@@ -28,23 +30,104 @@ func ParseTestSuiteFile(suiteFilePath, chartRoute string) (*TestSuite, error) {
 		return &suite, err
 	}
 
-	if err := yaml.Unmarshal(content, &suite); err != nil {
-		return &suite, err
+	return &suite, suite.parse(content)
+}
+
+// parseArchivedTestSuite parse a suite file packaged in the chart archive and returns TestSuite,
+// the values files of tests are read from the archive too
+func parseArchivedTestSuite(
+	chartPath, chartRoute, fileName string,
+	archivedFiles map[string][]byte,
+) (*TestSuite, error) {
+	suite := TestSuite{
+		chartRoute:     chartRoute,
+		definitionFile: filepath.Join(chartPath, fileName),
+		archivedFiles:  make(map[string][]byte, len(archivedFiles)),
+		archivedPath:   filepath.Join(chartRoute, fileName),
+	}
+	for name, content := range archivedFiles {
+		suite.archivedFiles[filepath.Join(chartPath, name)] = content
 	}
 
+	if err := suite.parse(archivedFiles[fileName]); err != nil {
+		return &suite, err
+	}
+	// the paths are read from disk, which don't exist for the suite in archive
+	if len(suite.Policies) > 0 || len(suite.CRDs) > 0 {
+		return &suite, fmt.Errorf(
+			"policies and crds are not supported in the test suite packaged in chart archive, set them with --policies and --crds",
+		)
+	}
 	return &suite, nil
 }
 
+func (s *TestSuite) parse(content []byte) error {
+	if err := yaml.Unmarshal(content, s); err != nil {
+		return err
+	}
+
+	// policies and crds paths are relative to the suite file
+	for _, paths := range [][]string{s.Policies, s.CRDs} {
+		for idx, relativePath := range paths {
+			if !filepath.IsAbs(relativePath) {
+				paths[idx] = filepath.Join(filepath.Dir(s.definitionFile), relativePath)
+			}
+		}
+	}
+	return nil
+}
+
 // TestSuite defines scope and templates to render and tests to run
 type TestSuite struct {
-	Name      string `yaml:"suite"`
+	Name string `yaml:"suite"`
+	// the route of chart to test like "parent-chart/charts/child-chart", for the suites in tests dir
+	Chart     string
 	Templates []string
-	Tests     []*TestJob
+	Policies  []string
+	CRDs      []string `yaml:"crds"`
+	// best practice rules checked on all manifests rendered in tests
+	BestPractices *BestPracticesConfig `yaml:"bestPractices"`
+	// the objects existing in cluster returned by `lookup` in all tests
+	KubernetesProvider *KubernetesProvider `yaml:"kubernetesProvider"`
+	// the kube version and api versions used in all tests if not set in test
+	Capabilities *CapabilitiesConfig
+	// the stub bodies of named templates defined in chart, overridden in all tests
+	TemplateOverrides map[string]string `yaml:"templateOverrides"`
+	Tests             []*TestJob
 	// where the test suite file located
 	definitionFile string
 	// route indicate which chart in the dependency hierarchy
 	// like "parant-chart", "parent-charts/charts/child-chart"
 	chartRoute string
+	// directories of kubernetes schemas used to validate manifests
+	schemaLocations []string
+	// whether to validate all manifests rendered with kubernetes schemas
+	validateSchema bool
+	// kube version given in cli
+	kubeVersion string
+	// file of api versions given in cli
+	apiVersionsFile string
+	// whether to check best practice rules, enabled in cli
+	checkBestPractices bool
+	// whether to render with the random and crypto functions seeded or stubbed, enabled in cli
+	deterministic bool
+	// the chart loaded with helm 3 to render with, nil if rendered with helm 2
+	helm3Chart *v3chart.Chart
+	// the previous version of chart to check the upgrade safety against, given in cli
+	previous *previousChart
+	// the aliases of subcharts in chartRoute, empty if the suite is not of an aliased subchart
+	aliases []string
+	// the files packaged with the suite in chart archive by their paths, nil if not packaged
+	archivedFiles map[string][]byte
+	// the path of packaged suite from the root chart, like "parent-chart/charts/child-chart/tests/a_test.yaml"
+	archivedPath string
+}
+
+// BestPracticesConfig configures the best practice rules of test suite,
+// the rules are checked if it's defined, unless Enabled is false
+type BestPracticesConfig struct {
+	Enabled                           *bool
+	validators.BestPracticesValidator `yaml:",inline"`
 }
 
 // Run runs all the test jobs defined in TestSuite
@@ -56,6 +139,9 @@ func (s *TestSuite) Run(
 	s.polishTestJobsPathInfo()
 
 	result.DisplayName = s.Name
+	if len(s.aliases) > 0 {
+		result.DisplayName = fmt.Sprintf("%s [%s]", s.Name, strings.Join(s.aliases, "/"))
+	}
 	result.FilePath = s.definitionFile
 
 	preparedChart, err := s.prepareChart(targetChart)
@@ -64,8 +150,14 @@ func (s *TestSuite) Run(
 		return result
 	}
 
+	var preparedHelm3Chart *v3chart.Chart
+	if s.helm3Chart != nil {
+		preparedHelm3Chart = prepareHelm3Chart(s.helm3Chart, preparedChart)
+	}
+
 	result.Passed, result.TestsResult = s.runTestJobs(
 		preparedChart,
+		preparedHelm3Chart,
 		snapshotCache,
 	)
 
@@ -73,27 +165,91 @@ func (s *TestSuite) Run(
 	return result
 }
 
+// createSnapshot returns the snapshot cache of suite, the suite packaged in chart archive is cached
+// under snapshotDir since the archive is not writable. The suite of aliased subchart is cached
+// separately like "service_test.my-alias.yaml.snap" for each alias
+func (s *TestSuite) createSnapshot(snapshotDir string, isUpdating bool) (*snapshot.Cache, error) {
+	if s.archivedFiles == nil {
+		return snapshot.CreateSnapshotOfSuite(s.aliasedPath(s.definitionFile), isUpdating)
+	}
+	return snapshot.CreateSnapshotAt(
+		snapshot.SnapshotFileOf(snapshotDir, s.aliasedPath(s.archivedPath)),
+		isUpdating,
+	)
+}
+
+func (s *TestSuite) aliasedPath(suitePath string) string {
+	if len(s.aliases) == 0 {
+		return suitePath
+	}
+	ext := filepath.Ext(suitePath)
+	return strings.TrimSuffix(suitePath, ext) + "." + strings.Join(s.aliases, ".") + ext
+}
+
 // fill file path related info of TestJob
 func (s *TestSuite) polishTestJobsPathInfo() {
+	crdSchemas := &crdSchemaCache{}
 	for _, test := range s.Tests {
 		test.chartRoute = s.chartRoute
 		test.definitionFile = s.definitionFile
+		test.archivedFiles = s.archivedFiles
+		test.deterministic = s.deterministic
+		test.suiteKubernetesProvider = s.KubernetesProvider
+		test.suiteCapabilities = s.Capabilities
+		test.suiteTemplateOverrides = s.TemplateOverrides
+		test.policies = s.Policies
+		test.schemaLocations = s.schemaLocations
+		test.validateSchema = s.validateSchema
+		test.crdFiles = s.CRDs
+		test.crdSchemas = crdSchemas
+		test.defaultKubeVersion = s.kubeVersion
+		test.defaultAPIVersionsFile = s.apiVersionsFile
+		test.previous = s.previous
+		test.bestPractices = s.bestPracticesValidator()
 		if len(s.Templates) > 0 {
 			test.defaultTemplateToAssert = s.Templates[0]
 		}
 	}
 }
 
+// bestPracticesValidator returns the validator of best practice rules, nil if not enabled
+func (s *TestSuite) bestPracticesValidator() *validators.BestPracticesValidator {
+	if s.BestPractices == nil {
+		if s.checkBestPractices {
+			return &validators.BestPracticesValidator{}
+		}
+		return nil
+	}
+	if s.BestPractices.Enabled != nil && !*s.BestPractices.Enabled {
+		return nil
+	}
+	return &s.BestPractices.BestPracticesValidator
+}
+
 func (s *TestSuite) prepareChart(targetChart *chart.Chart) (*chart.Chart, error) {
 	copiedChart := new(chart.Chart)
 	*copiedChart = *targetChart
 
 	suiteIsFromRootChart := len(strings.Split(s.chartRoute, string(filepath.Separator))) <= 1
 
-	if len(s.Templates) == 0 && suiteIsFromRootChart {
-		return copiedChart, nil
+	if len(s.Templates) > 0 || !suiteIsFromRootChart {
+		filteredTemplate, err := s.filterTemplates(targetChart, suiteIsFromRootChart)
+		if err != nil {
+			return &chart.Chart{}, err
+		}
+		copiedChart.Templates = filteredTemplate
 	}
 
+	// the named templates defined in chart are overridden with the stubs of suite
+	if overrides := templateOverridesOf(s.TemplateOverrides); overrides != nil {
+		copiedChart.Templates = withTemplateOverrides(copiedChart.Templates, overrides)
+	}
+
+	return copiedChart, nil
+}
+
+// filterTemplates returns the templates of suite and the ones with extension .tpl
+func (s *TestSuite) filterTemplates(targetChart *chart.Chart, suiteIsFromRootChart bool) ([]*chart.Template, error) {
 	filteredTemplate := make([]*chart.Template, 0, len(s.Templates))
 	// check templates and add them in chart dependencies, if from subchart leave it empty
 	if suiteIsFromRootChart {
@@ -107,7 +263,7 @@ func (s *TestSuite) prepareChart(targetChart *chart.Chart) (*chart.Chart, error)
 				}
 			}
 			if !found {
-				return &chart.Chart{}, fmt.Errorf(
+				return nil, fmt.Errorf(
 					"template file `templates/%s` not found in chart",
 					fileName,
 				)
@@ -121,19 +277,19 @@ func (s *TestSuite) prepareChart(targetChart *chart.Chart) (*chart.Chart, error)
 			filteredTemplate = append(filteredTemplate, template)
 		}
 	}
-	copiedChart.Templates = filteredTemplate
-
-	return copiedChart, nil
+	return filteredTemplate, nil
 }
 
 func (s *TestSuite) runTestJobs(
 	chart *chart.Chart,
+	helm3Chart *v3chart.Chart,
 	cache *snapshot.Cache,
 ) (bool, []*TestJobResult) {
 	suitePass := true
 	jobResults := make([]*TestJobResult, len(s.Tests))
 
 	for idx, testJob := range s.Tests {
+		testJob.helm3Chart = helm3Chart
 		jobResult := testJob.Run(chart, cache, &TestJobResult{Index: idx})
 		jobResults[idx] = jobResult
 
@@ -86,3 +86,87 @@ tests:
 	a.Equal(1, len(suiteResult.TestsResult))
 	a.Equal("test suite name", suiteResult.DisplayName)
 }
+
+func TestRunSuiteWithBestPractices(t *testing.T) {
+	c, _ := chartutil.Load("../__fixtures__/basic")
+	suiteDoc := `
+suite: test suite name
+templates:
+  - deployment.yaml
+bestPractices:
+  disable: [resources, probes, runAsNonRoot, noLatestTag, standardLabels]
+  warn: [team]
+  custom:
+    - name: team
+      path: metadata.labels.team
+    - name: deployment
+      kinds: [Deployment]
+      path: kind
+      pattern: ^Deployment$
+tests:
+  - it: should pass with warnings
+    asserts:
+      - equal:
+          path: kind
+          value: Deployment
+`
+	testSuite := TestSuite{}
+	yaml.Unmarshal([]byte(suiteDoc), &testSuite)
+
+	cache, _ := snapshot.CreateSnapshotOfSuite(path.Join(tmpdir, "my_test.yaml"), false)
+	suiteResult := testSuite.Run(c, cache, &TestSuiteResult{})
+
+	a := assert.New(t)
+	a.True(suiteResult.Passed)
+	a.Nil(suiteResult.ExecError)
+
+	assertsResult := suiteResult.TestsResult[0].AssertsResult
+	a.Equal(2, len(assertsResult))
+	a.True(assertsResult[1].Passed)
+	a.True(assertsResult[1].Warning)
+	a.Equal("- best practices check warning", assertsResult[1].CustomInfo)
+}
+
+func TestRunSuiteWithTemplateOverrides(t *testing.T) {
+	c, _ := chartutil.Load("../__fixtures__/basic")
+	suiteDoc := `
+suite: test suite name
+templates:
+  - deployment.yaml
+templateOverrides:
+  basic.fullname: fixed-name
+tests:
+  - it: should render with the stub of suite
+    asserts:
+      - equal:
+          path: metadata.name
+          value: fixed-name
+  - it: should render with the stub of test
+    templateOverrides:
+      basic.fullname: "{{ .Release.Name }}-stub"
+    asserts:
+      - equal:
+          path: metadata.name
+          value: RELEASE-NAME-stub
+  - it: should render with the empty stub
+    templateOverrides:
+      basic.fullname: ""
+    asserts:
+      - isNull:
+          path: metadata.name
+`
+	testSuite := TestSuite{}
+	yaml.Unmarshal([]byte(suiteDoc), &testSuite)
+
+	cache, _ := snapshot.CreateSnapshotOfSuite(path.Join(tmpdir, "template_overrides_test.yaml"), false)
+	suiteResult := testSuite.Run(c, cache, &TestSuiteResult{})
+
+	a := assert.New(t)
+	a.Nil(suiteResult.ExecError)
+	a.Equal(3, len(suiteResult.TestsResult))
+	for _, testResult := range suiteResult.TestsResult {
+		a.Nil(testResult.ExecError)
+		a.True(testResult.Passed, testResult.DisplayName)
+	}
+	a.True(suiteResult.Passed)
+}
@@ -0,0 +1,212 @@
+package unittest
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/lrills/helm-unittest/unittest/common"
+	v3chart "helm.sh/helm/v3/pkg/chart"
+	v3loader "helm.sh/helm/v3/pkg/chart/loader"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// gitRefPrefix the prefix of --upgrade-from to take the previous chart from a git ref, like "git:main"
+const gitRefPrefix = "git:"
+
+// previousChart the previous version of chart to check the upgrade safety against
+type previousChart struct {
+	chart      *chart.Chart
+	helm3Chart *v3chart.Chart
+	// where the previous chart from, like "git:main" or "./old-chart"
+	source string
+}
+
+// loadPreviousChart loads the previous version of the chart of chartPath given with --upgrade-from,
+// nil if not given or the chart not existing at the git ref, which is new and safe to upgrade
+func (tr *TestRunner) loadPreviousChart(chartPath string, currentChart *chart.Chart, usesHelm3 bool) (
+	*previousChart,
+	error,
+) {
+	source := tr.Config.UpgradeFrom
+	if source == "" {
+		return nil, nil
+	}
+
+	previousPath := source
+	if strings.HasPrefix(source, gitRefPrefix) {
+		extractedDir, err := ioutil.TempDir("", "helm-unittest-upgrade-from")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(extractedDir)
+
+		var existed bool
+		previousPath, existed, err = extractChartAtGitRef(chartPath, strings.TrimPrefix(source, gitRefPrefix), extractedDir)
+		if err != nil || !existed {
+			return nil, err
+		}
+	}
+
+	loadedChart, err := chartutil.Load(previousPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the chart to upgrade from %s: %s", source, err)
+	}
+	if loadedChart.Metadata.Name != currentChart.Metadata.Name {
+		return nil, fmt.Errorf(
+			"the chart to upgrade from %s is %s, not %s",
+			source, loadedChart.Metadata.Name, currentChart.Metadata.Name,
+		)
+	}
+
+	// the values of tests are for the current chart, which the previous values.schema.json may reject
+	removeValuesSchemaOfChart(loadedChart)
+	previous := &previousChart{chart: loadedChart, source: source}
+	if usesHelm3 {
+		if previous.helm3Chart, err = v3loader.Load(previousPath); err != nil {
+			return nil, fmt.Errorf("failed to load the chart to upgrade from %s: %s", source, err)
+		}
+		removeValuesSchemaOfHelm3Chart(previous.helm3Chart)
+	}
+	return previous, nil
+}
+
+// removeValuesSchemaOfChart removes the values.schema.json of chart and its dependencies
+func removeValuesSchemaOfChart(targetChart *chart.Chart) {
+	files := make([]*any.Any, 0, len(targetChart.Files))
+	for _, file := range targetChart.Files {
+		if file.TypeUrl != valuesSchemaFile {
+			files = append(files, file)
+		}
+	}
+	targetChart.Files = files
+	for _, dependency := range targetChart.Dependencies {
+		removeValuesSchemaOfChart(dependency)
+	}
+}
+
+// removeValuesSchemaOfHelm3Chart is removeValuesSchemaOfChart for the charts rendered with helm 3
+func removeValuesSchemaOfHelm3Chart(helm3Chart *v3chart.Chart) {
+	helm3Chart.Schema = nil
+	for _, dependency := range helm3Chart.Dependencies() {
+		removeValuesSchemaOfHelm3Chart(dependency)
+	}
+}
+
+// renderPreviousChart renders the previous chart with the same values, release and overrides of test
+func (t *TestJob) renderPreviousChart(userValues []byte) (map[string][]common.K8sManifest, error) {
+	previousJob := *t
+	previousJob.helm3Chart = t.previous.helm3Chart
+	// the previous chart is not prepared with the suite, so the overrides of suite are applied by test
+	previousJob.TemplateOverrides = t.mergedTemplateOverrides()
+
+	outputOfFiles, _, err := previousJob.renderChart(t.previous.chart, userValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render the chart to upgrade from %s: %s", t.previous.source, err)
+	}
+	return t.parseManifestsFromOutputOfFiles(outputOfFiles)
+}
+
+// extractChartAtGitRef extracts the chart of chartPath at the git ref of its working repository into dir,
+// returns the path of the chart extracted, and false if the chart not exists at the ref
+func extractChartAtGitRef(chartPath, ref, dir string) (string, bool, error) {
+	absPath, err := filepath.Abs(chartPath)
+	if err != nil {
+		return "", false, err
+	}
+	workingDir := absPath
+	if info, err := os.Stat(absPath); err != nil {
+		return "", false, err
+	} else if !info.IsDir() {
+		workingDir = filepath.Dir(absPath)
+	}
+
+	topLevel, err := runGit(workingDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", false, err
+	}
+	topLevel = strings.TrimSpace(topLevel)
+	// resolve the symlinks to be relative to the top level resolved by git
+	if resolvedPath, err := filepath.EvalSymlinks(absPath); err == nil {
+		absPath = resolvedPath
+	}
+	relativePath, err := filepath.Rel(topLevel, absPath)
+	if err != nil {
+		return "", false, err
+	}
+	relativePath = filepath.ToSlash(relativePath)
+
+	if _, err := runGit(topLevel, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return "", false, fmt.Errorf("%s is not a commit of the git repository %s", ref, topLevel)
+	}
+	// the chart at the root of repository always exists, otherwise it's listed if existing at the ref
+	if relativePath != "." {
+		listed, err := runGit(topLevel, "ls-tree", "--name-only", ref, "--", relativePath)
+		if err != nil {
+			return "", false, err
+		}
+		if strings.TrimSpace(listed) == "" {
+			return "", false, nil
+		}
+	}
+	archived, err := runGit(topLevel, "archive", "--format=tar", ref, "--", relativePath)
+	if err != nil {
+		return "", false, err
+	}
+	if err := extractTar([]byte(archived), dir); err != nil {
+		return "", false, err
+	}
+	return filepath.Join(dir, filepath.FromSlash(relativePath)), true, nil
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	command := exec.Command("git", args...)
+	command.Dir = dir
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %s %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// extractTar extracts the regular files in tar content into dir
+func extractTar(content []byte, dir string) error {
+	reader := tar.NewReader(bytes.NewReader(content))
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("invalid file %s in git archive", header.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(target, data, os.FileMode(header.Mode)); err != nil {
+			return err
+		}
+	}
+}
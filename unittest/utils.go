@@ -1,8 +1,13 @@
 package unittest
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
+
+	v3chart "helm.sh/helm/v3/pkg/chart"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
 )
 
 func spliteChartRoutes(routePath string) []string {
@@ -25,3 +30,119 @@ func scopeValuesWithRoutes(routes []string, values map[interface{}]interface{})
 	}
 	return values
 }
+
+// aliasesOfDependencies returns the names the dependencies of chart routed by when rendered,
+// which are the aliases declared in requirements.yaml, or in Chart.yaml of helm 3 chart
+func aliasesOfDependencies(targetChart *chart.Chart, helm3Chart *v3chart.Chart) map[string][]string {
+	aliases := make(map[string][]string)
+	addAlias := func(name, alias string) {
+		if alias == "" {
+			alias = name
+		}
+		for _, existed := range aliases[name] {
+			if existed == alias {
+				return
+			}
+		}
+		aliases[name] = append(aliases[name], alias)
+	}
+
+	if helm3Chart != nil {
+		for _, dependency := range helm3Chart.Metadata.Dependencies {
+			addAlias(dependency.Name, dependency.Alias)
+		}
+		return aliases
+	}
+
+	requirements, err := chartutil.LoadRequirements(targetChart)
+	if err != nil {
+		return aliases
+	}
+	for _, dependency := range requirements.Dependencies {
+		addAlias(dependency.Name, dependency.Alias)
+	}
+	return aliases
+}
+
+// routeNamesOfDependency returns the aliases of the dependency,
+// or its name if it's not declared in requirements
+func routeNamesOfDependency(aliases map[string][]string, name string) []string {
+	if len(aliases[name]) > 0 {
+		return aliases[name]
+	}
+	return []string{name}
+}
+
+// helm3DependencyOf returns the dependency of helm 3 chart with name, nil if not rendered with helm 3
+func helm3DependencyOf(helm3Chart *v3chart.Chart, name string) *v3chart.Chart {
+	if helm3Chart == nil {
+		return nil
+	}
+	for _, dependency := range helm3Chart.Dependencies() {
+		if dependency.Name() == name {
+			return dependency
+		}
+	}
+	return nil
+}
+
+// routeExists whether the dependencies of routes, routed by their aliases, exist in the chart
+func routeExists(targetChart *chart.Chart, helm3Chart *v3chart.Chart, routes []string) bool {
+	if len(routes) == 0 {
+		return true
+	}
+
+	aliases := aliasesOfDependencies(targetChart, helm3Chart)
+	for _, dependency := range targetChart.Dependencies {
+		name := dependency.Metadata.Name
+		for _, alias := range routeNamesOfDependency(aliases, name) {
+			if alias == routes[0] && routeExists(dependency, helm3DependencyOf(helm3Chart, name), routes[1:]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// chartOfRoute returns the chart or its dependency in the route like "parent-chart/charts/child-chart"
+func chartOfRoute(targetChart *chart.Chart, chartRoute string) (*chart.Chart, error) {
+	routedChart := targetChart
+	for _, route := range spliteChartRoutes(chartRoute)[1:] {
+		name := dependencyNameOfRoute(routedChart, nil, route)
+		var dependency *chart.Chart
+		for _, candidate := range routedChart.Dependencies {
+			if candidate.Metadata.Name == name {
+				dependency = candidate
+			}
+		}
+		if dependency == nil {
+			return nil, fmt.Errorf("chart %s not found", chartRoute)
+		}
+		routedChart = dependency
+	}
+	return routedChart, nil
+}
+
+// helm3ChartOfRoute is chartOfRoute for the charts rendered with helm 3
+func helm3ChartOfRoute(helm3Chart *v3chart.Chart, chartRoute string) (*v3chart.Chart, error) {
+	routedChart := helm3Chart
+	for _, route := range spliteChartRoutes(chartRoute)[1:] {
+		name := dependencyNameOfRoute(nil, routedChart, route)
+		if routedChart = helm3DependencyOf(routedChart, name); routedChart == nil {
+			return nil, fmt.Errorf("chart %s not found", chartRoute)
+		}
+	}
+	return routedChart, nil
+}
+
+// dependencyNameOfRoute returns the name of dependency routed by its alias or name
+func dependencyNameOfRoute(targetChart *chart.Chart, helm3Chart *v3chart.Chart, route string) string {
+	for name, aliases := range aliasesOfDependencies(targetChart, helm3Chart) {
+		for _, alias := range aliases {
+			if alias == route {
+				return name
+			}
+		}
+	}
+	return route
+}
@@ -0,0 +1,320 @@
+package validators
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	"github.com/lrills/helm-unittest/unittest/valueutils"
+)
+
+// ruleViolation a violation of best practice rule at path of the manifest
+type ruleViolation struct {
+	path    string
+	message string
+}
+
+// bestPracticeRule checks a manifest and returns the violations
+type bestPracticeRule func(manifest common.K8sManifest) []ruleViolation
+
+var builtinBestPracticeRules = map[string]bestPracticeRule{
+	"resources":      checkResources,
+	"probes":         checkProbes,
+	"runAsNonRoot":   checkRunAsNonRoot,
+	"noLatestTag":    checkNoLatestTag,
+	"standardLabels": checkStandardLabels,
+}
+
+// kinds whose pods are long running, so the probes are required
+var longRunningKinds = map[string]bool{
+	"Deployment":            true,
+	"StatefulSet":           true,
+	"DaemonSet":             true,
+	"ReplicaSet":            true,
+	"ReplicationController": true,
+}
+
+var standardLabels = []string{"app.kubernetes.io/name", "app.kubernetes.io/instance"}
+
+func checkResources(manifest common.K8sManifest) []ruleViolation {
+	template, ok := podTemplateOf(manifest)
+	if !ok {
+		return nil
+	}
+	violations := make([]ruleViolation, 0)
+	for _, container := range template.containers() {
+		resources := mapOf(container.container["resources"])
+		for _, field := range []string{"requests", "limits"} {
+			if len(mapOf(resources[field])) == 0 {
+				violations = append(violations, ruleViolation{
+					container.path + ".resources." + field, "should be set",
+				})
+			}
+		}
+	}
+	return violations
+}
+
+func checkProbes(manifest common.K8sManifest) []ruleViolation {
+	template, ok := podTemplateOf(manifest)
+	if kind, _ := manifest["kind"].(string); !ok || !longRunningKinds[kind] {
+		return nil
+	}
+	violations := make([]ruleViolation, 0)
+	for _, container := range template.containers() {
+		if container.init {
+			continue
+		}
+		for _, field := range []string{"livenessProbe", "readinessProbe"} {
+			if len(mapOf(container.container[field])) == 0 {
+				violations = append(violations, ruleViolation{container.path + "." + field, "should be set"})
+			}
+		}
+	}
+	return violations
+}
+
+func checkRunAsNonRoot(manifest common.K8sManifest) []ruleViolation {
+	template, ok := podTemplateOf(manifest)
+	if !ok {
+		return nil
+	}
+	podRunAsNonRoot := mapOf(template.spec["securityContext"])["runAsNonRoot"] == true
+	violations := make([]ruleViolation, 0)
+	for _, container := range template.containers() {
+		runAsNonRoot, set := mapOf(container.container["securityContext"])["runAsNonRoot"]
+		if runAsNonRoot == true || (!set && podRunAsNonRoot) {
+			continue
+		}
+		violations = append(violations, ruleViolation{
+			container.path + ".securityContext.runAsNonRoot",
+			"should be true, or set in spec.securityContext of pod",
+		})
+	}
+	return violations
+}
+
+func checkNoLatestTag(manifest common.K8sManifest) []ruleViolation {
+	template, ok := podTemplateOf(manifest)
+	if !ok {
+		return nil
+	}
+	violations := make([]ruleViolation, 0)
+	for _, container := range template.containers() {
+		image := stringOf(container.container["image"])
+		if strings.Contains(image, "@") {
+			continue
+		}
+		var tag string
+		name := image[strings.LastIndex(image, "/")+1:]
+		if idx := strings.LastIndex(name, ":"); idx >= 0 {
+			tag = name[idx+1:]
+		}
+		if tag == "" || tag == "latest" {
+			violations = append(violations, ruleViolation{
+				container.path + ".image",
+				fmt.Sprintf("%s should be pinned to a tag other than latest", image),
+			})
+		}
+	}
+	return violations
+}
+
+func checkStandardLabels(manifest common.K8sManifest) []ruleViolation {
+	if _, ok := manifest["kind"].(string); !ok {
+		return nil
+	}
+	labels := mapOf(mapOf(common.ConvertToJSONCompatible(manifest["metadata"]))["labels"])
+	violations := make([]ruleViolation, 0)
+	for _, label := range standardLabels {
+		if _, ok := labels[label]; !ok {
+			violations = append(violations, ruleViolation{"metadata.labels", "should have label " + label})
+		}
+	}
+	return violations
+}
+
+// CustomRule a best practice rule declared in test suite, the value at Path of manifests
+// should be set, and match Pattern if given. Only the manifests of Kinds are checked if given
+type CustomRule struct {
+	Name    string
+	Kinds   []string
+	Path    string
+	Pattern string
+	Message string
+}
+
+func (r CustomRule) rule() (bestPracticeRule, error) {
+	if r.Name == "" || r.Path == "" {
+		return nil, fmt.Errorf("name and path of custom rule are required")
+	}
+	var pattern *regexp.Regexp
+	if r.Pattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(r.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern of custom rule %s: %s", r.Name, err)
+		}
+	}
+
+	return func(manifest common.K8sManifest) []ruleViolation {
+		kind, _ := manifest["kind"].(string)
+		if len(r.Kinds) > 0 && !containsString(r.Kinds, kind) {
+			return nil
+		}
+		value, err := valueutils.GetValueOfSetPath(manifest, r.Path)
+		switch {
+		case err != nil || value == nil:
+			return []ruleViolation{{r.Path, r.messageOr("should be set")}}
+		case pattern != nil && !pattern.MatchString(fmt.Sprint(value)):
+			return []ruleViolation{{r.Path, r.messageOr("should match " + r.Pattern)}}
+		}
+		return nil
+	}, nil
+}
+
+func (r CustomRule) messageOr(defaultMessage string) string {
+	if r.Message != "" {
+		return r.Message
+	}
+	return defaultMessage
+}
+
+// RuleExemption exempts the manifests matched from Rule, or from all rules if Rule is empty.
+// The manifests are matched with all of Template, Kind and Name given
+type RuleExemption struct {
+	Rule     string
+	Template string
+	Kind     string
+	Name     string
+}
+
+func (e RuleExemption) exempts(rule string, manifest manifestOfFile) bool {
+	return (e.Rule == "" || e.Rule == rule) &&
+		(e.Template == "" || manifest.file == e.Template ||
+			strings.HasSuffix(manifest.file, "/templates/"+e.Template)) &&
+		(e.Kind == "" || manifest.kind() == e.Kind) &&
+		(e.Name == "" || manifest.name() == e.Name)
+}
+
+// BestPracticesValidator validate all manifests rendered in the test follow the built-in
+// best practice rules and the Custom ones, except the rules in Disable and the manifests Exempt.
+// The violations of rules in Warn are reported as warnings but not failures
+type BestPracticesValidator struct {
+	Disable []string
+	Warn    []string
+	Exempt  []RuleExemption
+	Custom  []CustomRule
+}
+
+// ChartWide implement ChartWideValidatable
+func (v BestPracticesValidator) ChartWide() {}
+
+func (v BestPracticesValidator) rules() (map[string]bestPracticeRule, error) {
+	rules := make(map[string]bestPracticeRule, len(builtinBestPracticeRules)+len(v.Custom))
+	for name, rule := range builtinBestPracticeRules {
+		rules[name] = rule
+	}
+	for _, custom := range v.Custom {
+		rule, err := custom.rule()
+		if err != nil {
+			return nil, err
+		}
+		if _, existed := rules[custom.Name]; existed {
+			return nil, fmt.Errorf("custom rule %s is declared duplicately", custom.Name)
+		}
+		rules[custom.Name] = rule
+	}
+
+	for _, names := range [][]string{v.Disable, v.Warn} {
+		for _, name := range names {
+			if _, ok := rules[name]; !ok {
+				return nil, fmt.Errorf("best practice rule %s not found", name)
+			}
+		}
+	}
+	for _, name := range v.Disable {
+		delete(rules, name)
+	}
+	return rules, nil
+}
+
+// Check returns the violations of rules as failures, and the ones of rules in Warn as warnings
+func (v BestPracticesValidator) Check(
+	manifestsOfFiles map[string][]common.K8sManifest,
+) (failures []string, warnings []string, err error) {
+	rules, err := v.rules()
+	if err != nil {
+		return nil, nil, err
+	}
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	failures = make([]string, 0)
+	warnings = make([]string, 0)
+	for _, manifest := range flattenManifestsOfFiles(manifestsOfFiles) {
+		for _, name := range names {
+			if v.exempted(name, manifest) {
+				continue
+			}
+			for _, violation := range rules[name](manifest.manifest) {
+				line := fmt.Sprintf(
+					"%s [%s] %s: %s", manifest.source(), name, violation.path, violation.message,
+				)
+				if containsString(v.Warn, name) {
+					warnings = append(warnings, line)
+				} else {
+					failures = append(failures, line)
+				}
+			}
+		}
+	}
+	return failures, warnings, nil
+}
+
+func (v BestPracticesValidator) exempted(rule string, manifest manifestOfFile) bool {
+	for _, exemption := range v.Exempt {
+		if exemption.exempts(rule, manifest) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v BestPracticesValidator) failInfo(failures []string, not bool) []string {
+	if not {
+		return []string{"Expected NOT to follow best practices, but no violations found"}
+	}
+
+	info := []string{"Expected to follow best practices, violations:"}
+	for _, failure := range failures {
+		info = append(info, "\t"+failure)
+	}
+	return info
+}
+
+// Validate implement Validatable
+func (v BestPracticesValidator) Validate(context *ValidateContext) (bool, []string) {
+	failures, _, err := v.Check(context.AllDocs)
+	if err != nil {
+		return false, splitInfof(errorFormat, err.Error())
+	}
+
+	if (len(failures) == 0) != context.Negative {
+		return true, []string{}
+	}
+	return false, v.failInfo(failures, context.Negative)
+}
+
+func containsString(list []string, s string) bool {
+	for _, ele := range list {
+		if ele == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package validators_test
+
+import (
+	"testing"
+
+	. "github.com/lrills/helm-unittest/unittest/validators"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	"github.com/stretchr/testify/assert"
+)
+
+var docsToTestBestPractices = map[string][]common.K8sManifest{
+	"my-chart/templates/deployment.yaml": {makeManifest(`
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app.kubernetes.io/name: web
+    app.kubernetes.io/instance: release
+spec:
+  template:
+    spec:
+      securityContext:
+        runAsNonRoot: true
+      containers:
+        - name: web
+          image: nginx
+          resources:
+            requests:
+              cpu: 100m
+          livenessProbe:
+            httpGet:
+              path: /
+          readinessProbe:
+            httpGet:
+              path: /
+`)},
+	"my-chart/templates/job.yaml": {makeManifest(`
+kind: Job
+metadata:
+  name: migrate
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          image: migrate:1.0.0
+          resources:
+            requests:
+              cpu: 100m
+            limits:
+              cpu: 100m
+`)},
+}
+
+func TestBestPracticesValidatorWhenOk(t *testing.T) {
+	v := BestPracticesValidator{
+		Disable: []string{"noLatestTag", "resources"},
+		Exempt:  []RuleExemption{{Template: "job.yaml"}},
+	}
+	pass, diff := v.Validate(&ValidateContext{AllDocs: docsToTestBestPractices})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestBestPracticesValidatorWhenFail(t *testing.T) {
+	v := BestPracticesValidator{}
+	pass, diff := v.Validate(&ValidateContext{AllDocs: docsToTestBestPractices})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expected to follow best practices, violations:",
+		"	my-chart/templates/deployment.yaml documents[0] Deployment/web [noLatestTag] spec.template.spec.containers[0].image: nginx should be pinned to a tag other than latest",
+		"	my-chart/templates/deployment.yaml documents[0] Deployment/web [resources] spec.template.spec.containers[0].resources.limits: should be set",
+		"	my-chart/templates/job.yaml documents[0] Job/migrate [runAsNonRoot] spec.template.spec.containers[0].securityContext.runAsNonRoot: should be true, or set in spec.securityContext of pod",
+		"	my-chart/templates/job.yaml documents[0] Job/migrate [standardLabels] metadata.labels: should have label app.kubernetes.io/name",
+		"	my-chart/templates/job.yaml documents[0] Job/migrate [standardLabels] metadata.labels: should have label app.kubernetes.io/instance",
+	}, diff)
+}
+
+func TestBestPracticesValidatorCheckWithWarnAndCustomRules(t *testing.T) {
+	v := BestPracticesValidator{
+		Disable: []string{"runAsNonRoot", "standardLabels"},
+		Warn:    []string{"noLatestTag", "team"},
+		Exempt:  []RuleExemption{{Rule: "resources", Kind: "Deployment", Name: "web"}},
+		Custom: []CustomRule{
+			{Name: "team", Path: "metadata.labels.team", Message: "should be labeled with team"},
+			{Name: "replicas", Kinds: []string{"Deployment"}, Path: "spec.replicas", Pattern: "^[2-9]$"},
+		},
+	}
+	failures, warnings, err := v.Check(docsToTestBestPractices)
+
+	a := assert.New(t)
+	a.Nil(err)
+	a.Equal([]string{
+		"my-chart/templates/deployment.yaml documents[0] Deployment/web [replicas] spec.replicas: should be set",
+	}, failures)
+	a.Equal([]string{
+		"my-chart/templates/deployment.yaml documents[0] Deployment/web [noLatestTag] spec.template.spec.containers[0].image: nginx should be pinned to a tag other than latest",
+		"my-chart/templates/deployment.yaml documents[0] Deployment/web [team] metadata.labels.team: should be labeled with team",
+		"my-chart/templates/job.yaml documents[0] Job/migrate [team] metadata.labels.team: should be labeled with team",
+	}, warnings)
+}
+
+func TestBestPracticesValidatorWhenRuleNotFound(t *testing.T) {
+	v := BestPracticesValidator{Disable: []string{"unknown"}}
+	pass, diff := v.Validate(&ValidateContext{AllDocs: docsToTestBestPractices})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{"Error:", "	best practice rule unknown not found"}, diff)
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/lrills/helm-unittest/unittest/common"
+	"github.com/lrills/helm-unittest/unittest/schema"
 	"github.com/lrills/helm-unittest/unittest/snapshot"
 	"github.com/pmezard/go-difflib/difflib"
 )
@@ -14,26 +15,57 @@ type SnapshotComparer interface {
 	CompareToSnapshot(content interface{}) *snapshot.CompareResult
 }
 
+// SchemaValidator provide ValidateManifest utility to validator
+type SchemaValidator interface {
+	ValidateManifest(manifest common.K8sManifest) ([]schema.Violation, error)
+}
+
 // ValidateContext the context passed to validators
 type ValidateContext struct {
-	Docs     []common.K8sManifest
-	Index    int
-	Negative bool
+	Docs  []common.K8sManifest
+	Index int
+	// whether documentIndex is given in assertion, for the validators asserting all documents if not
+	IndexGiven bool
+	Negative   bool
+	// paths of the rego policy files or directories for MatchPolicyValidator
+	Policies []string
+	// the release and values used to render, for ExpressionValidator
+	Release map[string]interface{}
+	Values  map[string]interface{}
+	// validate manifests with kubernetes schemas, for IsValidManifestValidator
+	SchemaValidator SchemaValidator
+	// the kube version in capabilities, for NotDeprecatedAPIValidator
+	KubeVersion string
+	// all manifests rendered in the test by file, for ChartWideValidatable
+	AllDocs map[string][]common.K8sManifest
+	// violations of values against values.schema.json of charts, for FailedValuesSchemaValidator
+	ValuesSchemaViolations []string
+	// all manifests rendered with the previous chart by file, for IsUpgradeSafeValidator
+	PreviousDocs map[string][]common.K8sManifest
 	SnapshotComparer
 }
 
-func (c *ValidateContext) getManifest() (common.K8sManifest, error) {
+// GetManifest returns the document at Index of Docs
+func (c *ValidateContext) GetManifest() (common.K8sManifest, error) {
 	if len(c.Docs) <= c.Index {
 		return nil, fmt.Errorf("documentIndex %d out of range", c.Index)
 	}
 	return c.Docs[c.Index], nil
 }
 
-// Validatable all validators must implement Validate method
+// Validatable all validators must implement Validate method,
+// it returns whether passed and the lines of info to show if failed
 type Validatable interface {
 	Validate(context *ValidateContext) (bool, []string)
 }
 
+// ChartWideValidatable validators validate with AllDocs, all manifests rendered in the test,
+// instead of the ones of template, so the template of assertion is not required
+type ChartWideValidatable interface {
+	Validatable
+	ChartWide()
+}
+
 // splitInfof split multi line string into array of string
 func splitInfof(format string, replacements ...string) []string {
 	intentedFormat := strings.Trim(format, "\t\n ")
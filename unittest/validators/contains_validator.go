@@ -37,7 +37,7 @@ Actual:
 
 // Validate implement Validatable
 func (v ContainsValidator) Validate(context *ValidateContext) (bool, []string) {
-	manifest, err := context.getManifest()
+	manifest, err := context.GetManifest()
 	if err != nil {
 		return false, splitInfof(errorFormat, err.Error())
 	}
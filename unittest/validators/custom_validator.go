@@ -0,0 +1,135 @@
+package validators
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+)
+
+// the time the executable of custom assertion is killed after, if Timeout not set
+const defaultCustomTimeout = 30 * time.Second
+
+// CustomValidator validate manifests with the external executable Command.
+// The manifests, Path and Parameters are written to its stdin as json, and the result is read
+// from its stdout as json like {"passed": bool, "failInfo": [string]}, which is negated if Negative
+type CustomValidator struct {
+	Command    string
+	Args       []string
+	Path       string
+	Parameters interface{}
+	// the duration like "10s" to kill the executable after, default to 30s
+	Timeout string
+}
+
+// customValidatorInput the json written to stdin of the executable
+type customValidatorInput struct {
+	Manifests     []interface{} `json:"manifests"`
+	DocumentIndex int           `json:"documentIndex"`
+	Path          string        `json:"path"`
+	Parameters    interface{}   `json:"parameters"`
+}
+
+// customValidatorOutput the json read from stdout of the executable
+type customValidatorOutput struct {
+	Passed   *bool    `json:"passed"`
+	FailInfo []string `json:"failInfo"`
+}
+
+func (v CustomValidator) failInfo(output customValidatorOutput, not bool) []string {
+	var notAnnotation string
+	if not {
+		notAnnotation = " NOT"
+	}
+	customFailFormat := `
+Command:%s
+Expected` + notAnnotation + ` to pass
+`
+	info := splitInfof(customFailFormat, v.commandLine())
+	for _, line := range output.FailInfo {
+		info = append(info, "\t"+line)
+	}
+	return info
+}
+
+func (v CustomValidator) commandLine() string {
+	return strings.Join(append([]string{v.Command}, v.Args...), " ")
+}
+
+func (v CustomValidator) timeout() (time.Duration, error) {
+	if v.Timeout == "" {
+		return defaultCustomTimeout, nil
+	}
+	timeout, err := time.ParseDuration(v.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %s of custom assertion, should be like 10s", v.Timeout)
+	}
+	return timeout, nil
+}
+
+func (v CustomValidator) execute(input customValidatorInput) (customValidatorOutput, error) {
+	var output customValidatorOutput
+
+	timeout, err := v.timeout()
+	if err != nil {
+		return output, err
+	}
+	stdin, err := json.Marshal(input)
+	if err != nil {
+		return output, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, v.Command, v.Args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return output, fmt.Errorf("`%s` timed out after %s", v.commandLine(), timeout)
+		}
+		return output, fmt.Errorf("`%s` failed: %s\n%s", v.commandLine(), err, stderr.String())
+	}
+
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return output, fmt.Errorf("`%s` returned invalid output: %s\n%s", v.commandLine(), err, stdout.String())
+	}
+	if output.Passed == nil {
+		return output, fmt.Errorf("`%s` returned no `passed` field:\n%s", v.commandLine(), stdout.String())
+	}
+	return output, nil
+}
+
+// Validate implement Validatable
+func (v CustomValidator) Validate(context *ValidateContext) (bool, []string) {
+	if v.Command == "" {
+		return false, splitInfof(errorFormat, "command of custom assertion is empty")
+	}
+
+	manifests := make([]interface{}, len(context.Docs))
+	for idx, doc := range context.Docs {
+		manifests[idx] = common.ConvertToJSONCompatible(doc)
+	}
+
+	output, err := v.execute(customValidatorInput{
+		Manifests:     manifests,
+		DocumentIndex: context.Index,
+		Path:          v.Path,
+		Parameters:    common.ConvertToJSONCompatible(v.Parameters),
+	})
+	if err != nil {
+		return false, splitInfof(errorFormat, err.Error())
+	}
+
+	if *output.Passed != context.Negative {
+		return true, []string{}
+	}
+	return false, v.failInfo(output, context.Negative)
+}
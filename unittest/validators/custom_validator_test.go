@@ -0,0 +1,156 @@
+package validators_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/lrills/helm-unittest/unittest/validators"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeCustomExecutable(t *testing.T, script string) string {
+	dir, err := ioutil.TempDir("", "custom_validator_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "check.sh")
+	if err := ioutil.WriteFile(file, []byte("#!/bin/sh\n"+script), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestCustomValidatorWhenOk(t *testing.T) {
+	manifest := makeManifest("kind: Pod")
+	command := makeCustomExecutable(t, `
+grep -q '"kind":"Pod"' && echo '{"passed": true}' || echo '{"passed": false}'
+`)
+	defer os.RemoveAll(filepath.Dir(command))
+
+	v := CustomValidator{Command: command}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs: []common.K8sManifest{manifest},
+	})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestCustomValidatorPassParameters(t *testing.T) {
+	manifest := makeManifest("kind: Pod")
+	command := makeCustomExecutable(t, `
+grep -q '"parameters":{"label":"team"}' && echo '{"passed": true}' || echo '{"passed": false}'
+`)
+	defer os.RemoveAll(filepath.Dir(command))
+
+	v := CustomValidator{
+		Command:    command,
+		Parameters: map[interface{}]interface{}{"label": "team"},
+	}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs: []common.K8sManifest{manifest},
+	})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestCustomValidatorWhenNegativeAndOk(t *testing.T) {
+	manifest := makeManifest("kind: Pod")
+	command := makeCustomExecutable(t, `
+cat > /dev/null
+echo '{"passed": false, "failInfo": ["label team is missing"]}'
+`)
+	defer os.RemoveAll(filepath.Dir(command))
+
+	v := CustomValidator{Command: command}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs:     []common.K8sManifest{manifest},
+		Negative: true,
+	})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestCustomValidatorWhenNegativeAndFail(t *testing.T) {
+	manifest := makeManifest("kind: Pod")
+	command := makeCustomExecutable(t, `
+cat > /dev/null
+echo '{"passed": true}'
+`)
+	defer os.RemoveAll(filepath.Dir(command))
+
+	v := CustomValidator{Command: command}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs:     []common.K8sManifest{manifest},
+		Negative: true,
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Command:	" + command,
+		"Expected NOT to pass",
+	}, diff)
+}
+
+func TestCustomValidatorWhenTimeout(t *testing.T) {
+	manifest := makeManifest("kind: Pod")
+	command := makeCustomExecutable(t, `
+exec sleep 10
+`)
+	defer os.RemoveAll(filepath.Dir(command))
+
+	v := CustomValidator{Command: command, Timeout: "100ms"}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs: []common.K8sManifest{manifest},
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Error:",
+		"	`" + command + "` timed out after 100ms",
+	}, diff)
+}
+
+func TestCustomValidatorWhenFail(t *testing.T) {
+	manifest := makeManifest("kind: Pod")
+	command := makeCustomExecutable(t, `
+cat > /dev/null
+echo '{"passed": false, "failInfo": ["label team is missing"]}'
+`)
+	defer os.RemoveAll(filepath.Dir(command))
+
+	v := CustomValidator{Command: command}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs: []common.K8sManifest{manifest},
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Command:	" + command,
+		"Expected to pass",
+		"	label team is missing",
+	}, diff)
+}
+
+func TestCustomValidatorWhenInvalidOutput(t *testing.T) {
+	manifest := makeManifest("kind: Pod")
+	command := makeCustomExecutable(t, `
+cat > /dev/null
+echo 'not json'
+`)
+	defer os.RemoveAll(filepath.Dir(command))
+
+	v := CustomValidator{Command: command}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs: []common.K8sManifest{manifest},
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, "Error:", diff[0])
+}
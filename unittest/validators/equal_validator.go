@@ -45,7 +45,7 @@ Diff:
 
 // Validate implement Validatable
 func (a EqualValidator) Validate(context *ValidateContext) (bool, []string) {
-	manifest, err := context.getManifest()
+	manifest, err := context.GetManifest()
 	if err != nil {
 		return false, splitInfof(errorFormat, err.Error())
 	}
@@ -0,0 +1,147 @@
+package validators
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/lrills/helm-unittest/unittest/common"
+)
+
+// ExpressionValidator validate the CEL expression Expr evaluated to true,
+// with the manifest as `object`, and the `release` and `values` used to render
+type ExpressionValidator struct {
+	Expr string
+}
+
+func (v ExpressionValidator) failInfo(subResults []string, not bool) []string {
+	var notAnnotation string
+	if not {
+		notAnnotation = " NOT"
+	}
+	expressionFailFormat := `
+Expression:%s
+Expected` + notAnnotation + ` to be true
+`
+	info := splitInfof(expressionFailFormat, v.Expr)
+	if len(subResults) > 0 {
+		info = append(info, "Evaluated:")
+		for _, subResult := range subResults {
+			info = append(info, "\t"+subResult)
+		}
+	}
+	return info
+}
+
+// evaluateExpression compile and evaluate expr with the activation
+func evaluateExpression(expr string, activation map[string]interface{}) (interface{}, error) {
+	env, err := cel.NewEnv(cel.Declarations(
+		decls.NewVar("object", decls.Dyn),
+		decls.NewVar("release", decls.Dyn),
+		decls.NewVar("values", decls.Dyn),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+
+	result, _, err := program.Eval(activation)
+	if err != nil {
+		return nil, err
+	}
+	return result.Value(), nil
+}
+
+// splitExpression split expr by the `&&` and `||` operators not enclosed in brackets or quotes
+func splitExpression(expr string) []string {
+	parts := make([]string, 0)
+	depth := 0
+	var quote rune
+	start := 0
+	runes := []rune(expr)
+	for idx := 0; idx < len(runes); idx++ {
+		r := runes[idx]
+		switch {
+		case quote != 0:
+			if r == '\\' {
+				idx++
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(' || r == '[' || r == '{':
+			depth++
+		case r == ')' || r == ']' || r == '}':
+			depth--
+		case depth == 0 && idx+1 < len(runes) &&
+			((r == '&' && runes[idx+1] == '&') || (r == '|' && runes[idx+1] == '|')):
+			parts = append(parts, strings.TrimSpace(string(runes[start:idx])))
+			start = idx + 2
+			idx++
+		}
+	}
+	return append(parts, strings.TrimSpace(string(runes[start:])))
+}
+
+// evaluateSubExpressions evaluate each operand of the top level `&&` and `||` operators
+func evaluateSubExpressions(expr string, activation map[string]interface{}) []string {
+	parts := splitExpression(expr)
+	if len(parts) <= 1 {
+		return []string{}
+	}
+
+	subResults := make([]string, len(parts))
+	for idx, part := range parts {
+		result, err := evaluateExpression(part, activation)
+		if err != nil {
+			subResults[idx] = fmt.Sprintf("%s => error: %s", part, err)
+		} else {
+			subResults[idx] = fmt.Sprintf("%s => %v", part, result)
+		}
+	}
+	return subResults
+}
+
+// Validate implement Validatable
+func (v ExpressionValidator) Validate(context *ValidateContext) (bool, []string) {
+	manifest, err := context.GetManifest()
+	if err != nil {
+		return false, splitInfof(errorFormat, err.Error())
+	}
+
+	activation := map[string]interface{}{
+		"object":  common.ConvertToJSONCompatible(manifest),
+		"release": common.ConvertToJSONCompatible(context.Release),
+		"values":  common.ConvertToJSONCompatible(context.Values),
+	}
+
+	result, err := evaluateExpression(v.Expr, activation)
+	if err != nil {
+		return false, splitInfof(errorFormat, err.Error())
+	}
+
+	passed, ok := result.(bool)
+	if !ok {
+		return false, splitInfof(errorFormat, fmt.Sprintf(
+			"expect expression '%s' to be evaluated as a bool, got:\n%s",
+			v.Expr,
+			common.TrustedMarshalYAML(result),
+		))
+	}
+
+	if passed != context.Negative {
+		return true, []string{}
+	}
+	return false, v.failInfo(evaluateSubExpressions(v.Expr, activation), context.Negative)
+}
@@ -0,0 +1,101 @@
+package validators_test
+
+import (
+	"testing"
+
+	. "github.com/lrills/helm-unittest/unittest/validators"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	"github.com/stretchr/testify/assert"
+)
+
+var docToTestExpression = `
+kind: Deployment
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+        - name: web
+          readinessProbe:
+            httpGet:
+              path: /
+        - name: sidecar
+`
+
+func TestExpressionValidatorWhenOk(t *testing.T) {
+	manifest := makeManifest(docToTestExpression)
+
+	v := ExpressionValidator{"object.spec.replicas >= values.minAvailable + 1 && release.Name == 'my-release'"}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs:    []common.K8sManifest{manifest},
+		Release: map[string]interface{}{"Name": "my-release"},
+		Values:  map[string]interface{}{"minAvailable": 2},
+	})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestExpressionValidatorWhenNegativeAndOk(t *testing.T) {
+	manifest := makeManifest(docToTestExpression)
+
+	v := ExpressionValidator{"object.spec.template.spec.containers.all(c, has(c.readinessProbe))"}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs:     []common.K8sManifest{manifest},
+		Negative: true,
+	})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestExpressionValidatorWhenFail(t *testing.T) {
+	manifest := makeManifest(docToTestExpression)
+
+	v := ExpressionValidator{"object.kind == 'Deployment' && (object.spec.replicas > 5 || object.spec.replicas < 2)"}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs: []common.K8sManifest{manifest},
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expression:	object.kind == 'Deployment' && (object.spec.replicas > 5 || object.spec.replicas < 2)",
+		"Expected to be true",
+		"Evaluated:",
+		"	object.kind == 'Deployment' => true",
+		"	(object.spec.replicas > 5 || object.spec.replicas < 2) => false",
+	}, diff)
+}
+
+func TestExpressionValidatorWhenNegativeAndFail(t *testing.T) {
+	manifest := makeManifest(docToTestExpression)
+
+	v := ExpressionValidator{"object.kind == 'Deployment'"}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs:     []common.K8sManifest{manifest},
+		Negative: true,
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expression:	object.kind == 'Deployment'",
+		"Expected NOT to be true",
+	}, diff)
+}
+
+func TestExpressionValidatorWhenNotBool(t *testing.T) {
+	manifest := makeManifest(docToTestExpression)
+
+	v := ExpressionValidator{"object.spec.replicas"}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs: []common.K8sManifest{manifest},
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Error:",
+		"	expect expression 'object.spec.replicas' to be evaluated as a bool, got:",
+		"	3",
+	}, diff)
+}
@@ -0,0 +1,55 @@
+package validators
+
+import "regexp"
+
+// FailedValuesSchemaValidator validate the values of test violate the values.schema.json of charts,
+// with a violation matching ErrorPattern if given
+type FailedValuesSchemaValidator struct {
+	ErrorPattern string
+}
+
+// ChartWide implement ChartWideValidatable
+func (v FailedValuesSchemaValidator) ChartWide() {}
+
+func (v FailedValuesSchemaValidator) failInfo(violations []string, not bool) []string {
+	info := make([]string, 0)
+	if v.ErrorPattern != "" {
+		info = append(info, "ErrorPattern:\t"+v.ErrorPattern)
+	}
+
+	if not {
+		info = append(info, "Expected values NOT to violate the values schema, violations:")
+	} else {
+		info = append(info, "Expected values to violate the values schema, violations:")
+	}
+	if len(violations) == 0 {
+		return append(info, "\tnone")
+	}
+	for _, violation := range violations {
+		info = append(info, "\t"+violation)
+	}
+	return info
+}
+
+// Validate implement Validatable
+func (v FailedValuesSchemaValidator) Validate(context *ValidateContext) (bool, []string) {
+	pattern, err := regexp.Compile(v.ErrorPattern)
+	if err != nil {
+		return false, splitInfof(errorFormat, err.Error())
+	}
+
+	matched := make([]string, 0)
+	for _, violation := range context.ValuesSchemaViolations {
+		if pattern.MatchString(violation) {
+			matched = append(matched, violation)
+		}
+	}
+
+	if (len(matched) > 0) != context.Negative {
+		return true, []string{}
+	}
+	if context.Negative {
+		return false, v.failInfo(matched, true)
+	}
+	return false, v.failInfo(context.ValuesSchemaViolations, false)
+}
@@ -0,0 +1,61 @@
+package validators_test
+
+import (
+	"testing"
+
+	. "github.com/lrills/helm-unittest/unittest/validators"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var violationsToTestFailedValuesSchema = []string{
+	"my-chart/replicaCount: Invalid type. Expected: integer, given: string",
+	"my-chart/image: image is required",
+}
+
+func TestFailedValuesSchemaValidatorWhenOk(t *testing.T) {
+	v := FailedValuesSchemaValidator{ErrorPattern: "replicaCount: Invalid type"}
+	pass, diff := v.Validate(&ValidateContext{ValuesSchemaViolations: violationsToTestFailedValuesSchema})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestFailedValuesSchemaValidatorWhenFail(t *testing.T) {
+	v := FailedValuesSchemaValidator{ErrorPattern: "tag is required"}
+	pass, diff := v.Validate(&ValidateContext{ValuesSchemaViolations: violationsToTestFailedValuesSchema})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"ErrorPattern:	tag is required",
+		"Expected values to violate the values schema, violations:",
+		"	my-chart/replicaCount: Invalid type. Expected: integer, given: string",
+		"	my-chart/image: image is required",
+	}, diff)
+}
+
+func TestFailedValuesSchemaValidatorWhenNoViolations(t *testing.T) {
+	v := FailedValuesSchemaValidator{}
+	pass, diff := v.Validate(&ValidateContext{ValuesSchemaViolations: []string{}})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expected values to violate the values schema, violations:",
+		"	none",
+	}, diff)
+}
+
+func TestFailedValuesSchemaValidatorWhenNegativeAndFail(t *testing.T) {
+	v := FailedValuesSchemaValidator{}
+	pass, diff := v.Validate(&ValidateContext{
+		ValuesSchemaViolations: violationsToTestFailedValuesSchema,
+		Negative:               true,
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expected values NOT to violate the values schema, violations:",
+		"	my-chart/replicaCount: Invalid type. Expected: integer, given: string",
+		"	my-chart/image: image is required",
+	}, diff)
+}
@@ -0,0 +1,77 @@
+package validators
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+)
+
+const (
+	hookAnnotation             = "helm.sh/hook"
+	hookWeightAnnotation       = "helm.sh/hook-weight"
+	hookDeletePolicyAnnotation = "helm.sh/hook-delete-policy"
+)
+
+// Hook the helm hook declared with the helm.sh/hook* annotations of manifest
+type Hook struct {
+	Events         []string
+	Weight         int
+	DeletePolicies []string
+}
+
+// HookOf returns the hook of manifest, and false if it's not a hook
+func HookOf(manifest common.K8sManifest) (Hook, bool) {
+	metadata := mapOf(common.ConvertToJSONCompatible(manifest["metadata"]))
+	annotations := mapOf(metadata["annotations"])
+	events := splitAnnotation(stringOf(annotations[hookAnnotation]))
+	if len(events) == 0 {
+		return Hook{}, false
+	}
+
+	// the weight is 0 if not given or invalid, like helm does
+	weight, _ := strconv.Atoi(strings.TrimSpace(stringOf(annotations[hookWeightAnnotation])))
+	return Hook{
+		Events:         events,
+		Weight:         weight,
+		DeletePolicies: splitAnnotation(stringOf(annotations[hookDeletePolicyAnnotation])),
+	}, true
+}
+
+// HasEvent whether the hook runs on event like "pre-install"
+func (h Hook) HasEvent(event string) bool {
+	return containsString(h.Events, event)
+}
+
+// DocumentsOfHook returns the documents which are hooks of event like "pre-upgrade"
+func DocumentsOfHook(docs []common.K8sManifest, event string) []common.K8sManifest {
+	hookDocs := make([]common.K8sManifest, 0, len(docs))
+	for _, doc := range docs {
+		if hook, ok := HookOf(doc); ok && hook.HasEvent(event) {
+			hookDocs = append(hookDocs, doc)
+		}
+	}
+	return hookDocs
+}
+
+// releasePhaseOf returns the phase of release like "install", "upgrade" or "rollback"
+func releasePhaseOf(release map[string]interface{}) string {
+	if isRollback, _ := release["IsRollback"].(bool); isRollback {
+		return "rollback"
+	}
+	if isUpgrade, _ := release["IsUpgrade"].(bool); isUpgrade {
+		return "upgrade"
+	}
+	return "install"
+}
+
+// splitAnnotation splits the comma separated values of annotation
+func splitAnnotation(annotation string) []string {
+	values := make([]string, 0)
+	for _, value := range strings.Split(annotation, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
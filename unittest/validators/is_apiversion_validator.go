@@ -21,7 +21,7 @@ func (v IsAPIVersionValidator) failInfo(actual interface{}, not bool) []string {
 
 // Validate implement Validatable
 func (v IsAPIVersionValidator) Validate(context *ValidateContext) (bool, []string) {
-	manifest, err := context.getManifest()
+	manifest, err := context.GetManifest()
 	if err != nil {
 		return false, splitInfof(errorFormat, err.Error())
 	}
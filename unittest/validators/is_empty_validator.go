@@ -28,7 +28,7 @@ Expected` + notAnnotation + ` to be empty, got:
 
 // Validate implement Validatable
 func (v IsEmptyValidator) Validate(context *ValidateContext) (bool, []string) {
-	manifest, err := context.getManifest()
+	manifest, err := context.GetManifest()
 	if err != nil {
 		return false, splitInfof(errorFormat, err.Error())
 	}
@@ -0,0 +1,83 @@
+package validators
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IsHookValidator validate the manifest is a helm hook, running on all Events,
+// of Weight and with all DeletePolicies if given
+type IsHookValidator struct {
+	Events         []string
+	Weight         *int
+	DeletePolicies []string
+}
+
+func (v IsHookValidator) expected() string {
+	expected := make([]string, 0, 3)
+	if len(v.Events) > 0 {
+		expected = append(expected, "events: "+strings.Join(v.Events, ","))
+	}
+	if v.Weight != nil {
+		expected = append(expected, fmt.Sprintf("weight: %d", *v.Weight))
+	}
+	if len(v.DeletePolicies) > 0 {
+		expected = append(expected, "deletePolicies: "+strings.Join(v.DeletePolicies, ","))
+	}
+	if len(expected) == 0 {
+		return "hook"
+	}
+	return "hook of " + strings.Join(expected, " ")
+}
+
+func (v IsHookValidator) failInfo(hook Hook, isHook bool, not bool) []string {
+	var notAnnotation string
+	if not {
+		notAnnotation = " NOT"
+	}
+	isHookFailFormat := "Expected" + notAnnotation + " to be:%s"
+	if !isHook {
+		return splitInfof(isHookFailFormat+"\nActual:%s", v.expected(), "not a hook")
+	}
+	return splitInfof(
+		isHookFailFormat+"\nActual:%s",
+		v.expected(),
+		fmt.Sprintf(
+			"hook of events: %s weight: %d deletePolicies: %s",
+			strings.Join(hook.Events, ","),
+			hook.Weight,
+			strings.Join(hook.DeletePolicies, ","),
+		),
+	)
+}
+
+func (v IsHookValidator) matches(hook Hook) bool {
+	for _, event := range v.Events {
+		if !hook.HasEvent(event) {
+			return false
+		}
+	}
+	if v.Weight != nil && *v.Weight != hook.Weight {
+		return false
+	}
+	for _, policy := range v.DeletePolicies {
+		if !containsString(hook.DeletePolicies, policy) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate implement Validatable
+func (v IsHookValidator) Validate(context *ValidateContext) (bool, []string) {
+	manifest, err := context.GetManifest()
+	if err != nil {
+		return false, splitInfof(errorFormat, err.Error())
+	}
+
+	hook, isHook := HookOf(manifest)
+	if (isHook && v.matches(hook)) != context.Negative {
+		return true, []string{}
+	}
+	return false, v.failInfo(hook, isHook, context.Negative)
+}
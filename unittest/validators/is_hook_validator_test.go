@@ -0,0 +1,80 @@
+package validators_test
+
+import (
+	"testing"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	. "github.com/lrills/helm-unittest/unittest/validators"
+	"github.com/stretchr/testify/assert"
+)
+
+var hookDoc = `
+kind: Job
+metadata:
+  name: migration
+  annotations:
+    helm.sh/hook: pre-install, pre-upgrade
+    helm.sh/hook-weight: "-5"
+    helm.sh/hook-delete-policy: before-hook-creation,hook-succeeded
+`
+
+func TestIsHookValidatorWhenOk(t *testing.T) {
+	weight := -5
+	validator := IsHookValidator{
+		Events:         []string{"pre-upgrade"},
+		Weight:         &weight,
+		DeletePolicies: []string{"hook-succeeded"},
+	}
+	pass, diff := validator.Validate(&ValidateContext{
+		Docs: []common.K8sManifest{makeManifest(hookDoc)},
+	})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestIsHookValidatorWhenNegativeAndOk(t *testing.T) {
+	validator := IsHookValidator{}
+	pass, diff := validator.Validate(&ValidateContext{
+		Docs:     []common.K8sManifest{makeManifest("kind: Deployment")},
+		Negative: true,
+	})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestIsHookValidatorWhenFail(t *testing.T) {
+	weight := 1
+	validator := IsHookValidator{Events: []string{"post-upgrade"}, Weight: &weight}
+	pass, diff := validator.Validate(&ValidateContext{
+		Docs: []common.K8sManifest{makeManifest(hookDoc)},
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expected to be:	hook of events: post-upgrade weight: 1",
+		"Actual:	hook of events: pre-install,pre-upgrade weight: -5 deletePolicies: before-hook-creation,hook-succeeded",
+	}, diff)
+}
+
+func TestIsHookValidatorWhenNotHook(t *testing.T) {
+	validator := IsHookValidator{}
+	pass, diff := validator.Validate(&ValidateContext{
+		Docs: []common.K8sManifest{makeManifest("kind: Deployment")},
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expected to be:	hook",
+		"Actual:	not a hook",
+	}, diff)
+}
+
+func TestDocumentsOfHook(t *testing.T) {
+	docs := []common.K8sManifest{makeManifest("kind: Deployment"), makeManifest(hookDoc)}
+
+	a := assert.New(t)
+	a.Equal(docs[1:], DocumentsOfHook(docs, "pre-upgrade"))
+	a.Empty(DocumentsOfHook(docs, "post-install"))
+}
@@ -21,7 +21,7 @@ func (v IsKindValidator) failInfo(actual interface{}, not bool) []string {
 
 // Validate implement Validatable
 func (v IsKindValidator) Validate(context *ValidateContext) (bool, []string) {
-	manifest, err := context.getManifest()
+	manifest, err := context.GetManifest()
 	if err != nil {
 		return false, splitInfof(errorFormat, err.Error())
 	}
@@ -26,7 +26,7 @@ Expected` + notAnnotation + ` to be null, got:
 
 // Validate implement Validatable
 func (v IsNullValidator) Validate(context *ValidateContext) (bool, []string) {
-	manifest, err := context.getManifest()
+	manifest, err := context.GetManifest()
 	if err != nil {
 		return false, splitInfof(errorFormat, err.Error())
 	}
@@ -0,0 +1,124 @@
+package validators
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	"github.com/lrills/helm-unittest/unittest/valueutils"
+)
+
+// the fields of kinds which can't be changed by `helm upgrade`
+var immutableFieldsOfKinds = map[string][]string{
+	"Deployment":  {"spec.selector"},
+	"ReplicaSet":  {"spec.selector"},
+	"DaemonSet":   {"spec.selector"},
+	"StatefulSet": {"spec.selector", "spec.serviceName", "spec.volumeClaimTemplates", "spec.podManagementPolicy"},
+	"Job":         {"spec.selector", "spec.template"},
+	"Service":     {"spec.clusterIP"},
+	"PersistentVolumeClaim": {
+		"spec.accessModes", "spec.storageClassName", "spec.volumeName",
+		"spec.volumeMode", "spec.selector", "spec.dataSource",
+	},
+	"StorageClass":       {"provisioner", "parameters", "reclaimPolicy", "volumeBindingMode"},
+	"RoleBinding":        {"roleRef"},
+	"ClusterRoleBinding": {"roleRef"},
+}
+
+// the data fields of kinds which can't be changed once the previous one is `immutable: true`
+var immutableDataFieldsOfKinds = map[string][]string{
+	"ConfigMap": {"data", "binaryData"},
+	"Secret":    {"data", "stringData"},
+}
+
+// IsUpgradeSafeValidator validate the immutable fields of all manifests rendered in the test,
+// like spec.selector of Deployment, are not changed from the ones of the same kind, namespace
+// and name rendered with the previous chart, so that `helm upgrade` won't be rejected
+type IsUpgradeSafeValidator struct{}
+
+// ChartWide implement ChartWideValidatable
+func (v IsUpgradeSafeValidator) ChartWide() {}
+
+func (v IsUpgradeSafeValidator) failInfo(changes []string, not bool) []string {
+	if not {
+		return splitInfof("Expected NOT to be safe to upgrade, but no immutable field changed")
+	}
+
+	info := []string{"Expected to be safe to upgrade, immutable fields changed:"}
+	for _, change := range changes {
+		info = append(info, "\t"+change)
+	}
+	return info
+}
+
+// Validate implement Validatable
+func (v IsUpgradeSafeValidator) Validate(context *ValidateContext) (bool, []string) {
+	if context.PreviousDocs == nil {
+		return false, splitInfof(errorFormat, "no previous chart to upgrade from, given with --upgrade-from")
+	}
+
+	previousManifests := make(map[string]manifestOfFile)
+	for _, manifest := range flattenManifestsOfFiles(context.PreviousDocs) {
+		previousManifests[identityOf(manifest)] = manifest
+	}
+
+	changes := make([]string, 0)
+	for _, manifest := range flattenManifestsOfFiles(context.AllDocs) {
+		previous, ok := previousManifests[identityOf(manifest)]
+		if !ok {
+			continue
+		}
+		for _, field := range immutableFieldsOf(previous) {
+			previousValue := valueOfField(previous.manifest, field)
+			currentValue := valueOfField(manifest.manifest, field)
+			if !reflect.DeepEqual(previousValue, currentValue) {
+				changes = append(changes, fmt.Sprintf(
+					"%s %s changed from %s to %s",
+					manifest.source(), field, compactJSON(previousValue), compactJSON(currentValue),
+				))
+			}
+		}
+	}
+
+	if (len(changes) == 0) != context.Negative {
+		return true, []string{}
+	}
+	return false, v.failInfo(changes, context.Negative)
+}
+
+// identityOf returns the kind, namespace and name identifying the resource between releases
+func identityOf(manifest manifestOfFile) string {
+	namespace := stringOf(mapOf(common.ConvertToJSONCompatible(manifest.manifest["metadata"]))["namespace"])
+	return fmt.Sprintf("%s/%s/%s", manifest.kind(), namespace, manifest.name())
+}
+
+// immutableFieldsOf returns the immutable fields of the previous manifest
+func immutableFieldsOf(previous manifestOfFile) []string {
+	fields := immutableFieldsOfKinds[previous.kind()]
+	if immutable, _ := previous.manifest["immutable"].(bool); immutable {
+		fields = append(fields, immutableDataFieldsOfKinds[previous.kind()]...)
+	}
+	return fields
+}
+
+// valueOfField returns the value of field, the missing or empty value is nil
+// since it's left to be defaulted by kubernetes
+func valueOfField(manifest common.K8sManifest, field string) interface{} {
+	value, err := valueutils.GetValueOfSetPath(manifest, field)
+	if err != nil || value == "" {
+		return nil
+	}
+	return common.ConvertToJSONCompatible(value)
+}
+
+func compactJSON(value interface{}) string {
+	if value == nil {
+		return "<none>"
+	}
+	content, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(content)
+}
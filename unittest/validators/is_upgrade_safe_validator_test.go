@@ -0,0 +1,154 @@
+package validators_test
+
+import (
+	"testing"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	. "github.com/lrills/helm-unittest/unittest/validators"
+	"github.com/stretchr/testify/assert"
+)
+
+var previousDocsToUpgrade = map[string][]common.K8sManifest{
+	"templates/deployment.yaml": {makeManifest(`
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: web
+`)},
+	"templates/service.yaml": {makeManifest(`
+kind: Service
+metadata:
+  name: web
+spec:
+  clusterIP: ""
+`)},
+}
+
+func TestIsUpgradeSafeValidatorWhenOk(t *testing.T) {
+	validator := IsUpgradeSafeValidator{}
+	pass, diff := validator.Validate(&ValidateContext{
+		AllDocs: map[string][]common.K8sManifest{
+			"templates/deployment.yaml": {makeManifest(`
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+  selector:
+    matchLabels:
+      app: web
+`)},
+			"templates/service.yaml": {makeManifest(`
+kind: Service
+metadata:
+  name: web
+`)},
+			"templates/worker.yaml": {makeManifest(`
+kind: Deployment
+metadata:
+  name: worker
+spec:
+  selector:
+    matchLabels:
+      app: worker
+`)},
+		},
+		PreviousDocs: previousDocsToUpgrade,
+	})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestIsUpgradeSafeValidatorWhenNegativeAndOk(t *testing.T) {
+	validator := IsUpgradeSafeValidator{}
+	pass, diff := validator.Validate(&ValidateContext{
+		AllDocs: map[string][]common.K8sManifest{
+			"templates/service.yaml": {makeManifest(`
+kind: Service
+metadata:
+  name: web
+spec:
+  clusterIP: None
+`)},
+		},
+		PreviousDocs: previousDocsToUpgrade,
+		Negative:     true,
+	})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestIsUpgradeSafeValidatorWhenFail(t *testing.T) {
+	validator := IsUpgradeSafeValidator{}
+	pass, diff := validator.Validate(&ValidateContext{
+		AllDocs: map[string][]common.K8sManifest{
+			"templates/web.yaml": {makeManifest(`
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+      tier: frontend
+`)},
+		},
+		PreviousDocs: previousDocsToUpgrade,
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expected to be safe to upgrade, immutable fields changed:",
+		`	templates/web.yaml documents[0] Deployment/web spec.selector changed from {"matchLabels":{"app":"web"}} to {"matchLabels":{"app":"web","tier":"frontend"}}`,
+	}, diff)
+}
+
+func TestIsUpgradeSafeValidatorWhenImmutableSecretChanged(t *testing.T) {
+	validator := IsUpgradeSafeValidator{}
+	pass, diff := validator.Validate(&ValidateContext{
+		AllDocs: map[string][]common.K8sManifest{
+			"templates/secret.yaml": {makeManifest(`
+kind: Secret
+metadata:
+  name: web
+data:
+  password: bmV3
+`)},
+		},
+		PreviousDocs: map[string][]common.K8sManifest{
+			"templates/secret.yaml": {makeManifest(`
+kind: Secret
+metadata:
+  name: web
+immutable: true
+data:
+  password: b2xk
+`)},
+		},
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expected to be safe to upgrade, immutable fields changed:",
+		`	templates/secret.yaml documents[0] Secret/web data changed from {"password":"b2xk"} to {"password":"bmV3"}`,
+	}, diff)
+}
+
+func TestIsUpgradeSafeValidatorWhenNoPreviousDocs(t *testing.T) {
+	validator := IsUpgradeSafeValidator{}
+	pass, diff := validator.Validate(&ValidateContext{
+		AllDocs: previousDocsToUpgrade,
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Error:",
+		"	no previous chart to upgrade from, given with --upgrade-from",
+	}, diff)
+}
@@ -0,0 +1,43 @@
+package validators
+
+import (
+	"fmt"
+)
+
+// IsValidManifestValidator validate all manifests rendered form template with the kubernetes schemas
+type IsValidManifestValidator struct{}
+
+func (v IsValidManifestValidator) failInfo(violations []string, not bool) []string {
+	if not {
+		return splitInfof("Expected NOT to be valid manifests, but no violation found")
+	}
+
+	info := []string{"Expected to be valid manifests, violations:"}
+	for _, violation := range violations {
+		info = append(info, "\t"+violation)
+	}
+	return info
+}
+
+// Validate implement Validatable
+func (v IsValidManifestValidator) Validate(context *ValidateContext) (bool, []string) {
+	if context.SchemaValidator == nil {
+		return false, splitInfof(errorFormat, "no schema validator available")
+	}
+
+	violations := make([]string, 0)
+	for idx, manifest := range context.Docs {
+		manifestViolations, err := context.SchemaValidator.ValidateManifest(manifest)
+		if err != nil {
+			return false, splitInfof(errorFormat, err.Error())
+		}
+		for _, violation := range manifestViolations {
+			violations = append(violations, fmt.Sprintf("documents[%d]%s", idx, violation.String()))
+		}
+	}
+
+	if (len(violations) == 0) != context.Negative {
+		return true, []string{}
+	}
+	return false, v.failInfo(violations, context.Negative)
+}
@@ -0,0 +1,62 @@
+package validators_test
+
+import (
+	"testing"
+
+	. "github.com/lrills/helm-unittest/unittest/validators"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	"github.com/lrills/helm-unittest/unittest/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSchemaValidator map[string][]schema.Violation
+
+func (v fakeSchemaValidator) ValidateManifest(manifest common.K8sManifest) ([]schema.Violation, error) {
+	return v[manifest["kind"].(string)], nil
+}
+
+var schemaValidatorToTestIsValidManifest = fakeSchemaValidator{
+	"Deployment": {{Pointer: "/spec/imagePullPolicy", Message: "Additional property imagePullPolicy is not allowed"}},
+}
+
+func TestIsValidManifestValidatorWhenOk(t *testing.T) {
+	manifest := makeManifest("kind: Service")
+
+	v := IsValidManifestValidator{}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs:            []common.K8sManifest{manifest},
+		SchemaValidator: schemaValidatorToTestIsValidManifest,
+	})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestIsValidManifestValidatorWhenFail(t *testing.T) {
+	v := IsValidManifestValidator{}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs:            []common.K8sManifest{makeManifest("kind: Service"), makeManifest("kind: Deployment")},
+		SchemaValidator: schemaValidatorToTestIsValidManifest,
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expected to be valid manifests, violations:",
+		"	documents[1]/spec/imagePullPolicy: Additional property imagePullPolicy is not allowed",
+	}, diff)
+}
+
+func TestIsValidManifestValidatorWhenNegativeAndFail(t *testing.T) {
+	v := IsValidManifestValidator{}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs:            []common.K8sManifest{makeManifest("kind: Service")},
+		Negative:        true,
+		SchemaValidator: schemaValidatorToTestIsValidManifest,
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expected NOT to be valid manifests, but no violation found",
+	}, diff)
+}
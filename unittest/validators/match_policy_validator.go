@@ -0,0 +1,156 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+const defaultPolicyQuery = "data.main.deny"
+
+// the queries prepared with the policies loaded, keyed by the query and policies,
+// so the policies of a suite are loaded once for all its tests
+var preparedQueries = map[string]rego.PreparedEvalQuery{}
+var preparedQueriesLock sync.Mutex
+
+// MatchPolicyValidator validate manifests against the rego policies in ValidateContext.Policies,
+// every message returned by Query (default to "data.main.deny") is a violation
+type MatchPolicyValidator struct {
+	Query string
+}
+
+func (v MatchPolicyValidator) query() string {
+	if v.Query != "" {
+		return v.Query
+	}
+	return defaultPolicyQuery
+}
+
+func (v MatchPolicyValidator) failInfo(violations []string, not bool) []string {
+	if not {
+		return splitInfof(`
+Query:%s
+Expected NOT to match policies, but no violation found
+`, v.query())
+	}
+
+	info := splitInfof(`
+Query:%s
+Expected to match policies, violations:
+`, v.query())
+	for _, violation := range violations {
+		info = append(info, "\t"+violation)
+	}
+	return info
+}
+
+// prepare returns the query prepared with policies, which is cached for the same query and policies
+func (v MatchPolicyValidator) prepare(ctx context.Context, policies []string) (rego.PreparedEvalQuery, error) {
+	key := strings.Join(append([]string{v.query()}, policies...), "\x00")
+	preparedQueriesLock.Lock()
+	defer preparedQueriesLock.Unlock()
+	if prepared, ok := preparedQueries[key]; ok {
+		return prepared, nil
+	}
+
+	prepared, err := rego.New(
+		rego.Query(v.query()),
+		rego.Load(policies, nil),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return prepared, err
+	}
+	preparedQueries[key] = prepared
+	return prepared, nil
+}
+
+// evaluate run the query against each manifest and collect the violation messages,
+// prefixed with the index of manifest in indexes
+func (v MatchPolicyValidator) evaluate(
+	policies []string,
+	manifests []common.K8sManifest,
+	indexes []int,
+) ([]string, error) {
+	ctx := context.Background()
+	prepared, err := v.prepare(ctx, policies)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := make([]string, 0)
+	for idx, manifest := range manifests {
+		resultSet, err := prepared.Eval(ctx, rego.EvalInput(common.ConvertToJSONCompatible(manifest)))
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range resultSet {
+			for _, expression := range result.Expressions {
+				for _, message := range policyMessages(expression.Value) {
+					violations = append(violations, fmt.Sprintf("[%d] %s", indexes[idx], message))
+				}
+			}
+		}
+	}
+	return violations, nil
+}
+
+// policyMessages flatten the value of query result into messages,
+// both `deny[msg]` and `deny[{"msg": msg}]` forms are accepted
+func policyMessages(value interface{}) []string {
+	switch v := value.(type) {
+	case []interface{}:
+		messages := make([]string, 0, len(v))
+		for _, ele := range v {
+			messages = append(messages, policyMessages(ele)...)
+		}
+		return messages
+	case map[string]interface{}:
+		if msg, ok := v["msg"]; ok {
+			return []string{fmt.Sprintf("%v", msg)}
+		}
+		return []string{common.TrustedMarshalYAML(v)}
+	case bool:
+		if v {
+			return []string{"denied"}
+		}
+		return []string{}
+	case nil:
+		return []string{}
+	default:
+		return []string{fmt.Sprintf("%v", v)}
+	}
+}
+
+// Validate implement Validatable
+func (v MatchPolicyValidator) Validate(context *ValidateContext) (bool, []string) {
+	if len(context.Policies) == 0 {
+		return false, splitInfof(errorFormat, "no policies defined in test suite or with --policies")
+	}
+
+	// all documents are asserted unless documentIndex given
+	manifests, indexes := context.Docs, make([]int, len(context.Docs))
+	for idx := range indexes {
+		indexes[idx] = idx
+	}
+	if context.IndexGiven {
+		manifest, err := context.GetManifest()
+		if err != nil {
+			return false, splitInfof(errorFormat, err.Error())
+		}
+		manifests, indexes = []common.K8sManifest{manifest}, []int{context.Index}
+	}
+
+	violations, err := v.evaluate(context.Policies, manifests, indexes)
+	if err != nil {
+		return false, splitInfof(errorFormat, err.Error())
+	}
+
+	if (len(violations) == 0) != context.Negative {
+		return true, []string{}
+	}
+	return false, v.failInfo(violations, context.Negative)
+}
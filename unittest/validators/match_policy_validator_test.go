@@ -0,0 +1,130 @@
+package validators_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/lrills/helm-unittest/unittest/validators"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	"github.com/stretchr/testify/assert"
+)
+
+var policyToTestMatchPolicy = `
+package main
+
+deny[msg] {
+	not input.metadata.labels.team
+	msg := sprintf("%s must have label team", [input.kind])
+}
+`
+
+func makePolicyDir(t *testing.T, policy string) string {
+	dir, err := ioutil.TempDir("", "match_policy_validator_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "policy.rego"), []byte(policy), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestMatchPolicyValidatorWhenOk(t *testing.T) {
+	manifest := makeManifest(`
+kind: Pod
+metadata:
+  labels:
+    team: foo
+`)
+
+	policyDir := makePolicyDir(t, policyToTestMatchPolicy)
+	defer os.RemoveAll(policyDir)
+
+	v := MatchPolicyValidator{}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs:     []common.K8sManifest{manifest},
+		Policies: []string{policyDir},
+	})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestMatchPolicyValidatorWhenFail(t *testing.T) {
+	manifest := makeManifest("kind: Pod")
+
+	policyDir := makePolicyDir(t, policyToTestMatchPolicy)
+	defer os.RemoveAll(policyDir)
+
+	v := MatchPolicyValidator{}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs:     []common.K8sManifest{manifest},
+		Policies: []string{policyDir},
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Query:	data.main.deny",
+		"Expected to match policies, violations:",
+		"	[0] Pod must have label team",
+	}, diff)
+}
+
+func TestMatchPolicyValidatorWhenNegativeAndOk(t *testing.T) {
+	manifest := makeManifest("kind: Pod")
+
+	policyDir := makePolicyDir(t, policyToTestMatchPolicy)
+	defer os.RemoveAll(policyDir)
+
+	v := MatchPolicyValidator{}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs:     []common.K8sManifest{manifest},
+		Policies: []string{policyDir},
+		Negative: true,
+	})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestMatchPolicyValidatorWithDocumentIndex(t *testing.T) {
+	manifests := []common.K8sManifest{
+		makeManifest("kind: Pod"),
+		makeManifest("kind: Service"),
+	}
+	policyDir := makePolicyDir(t, policyToTestMatchPolicy)
+	defer os.RemoveAll(policyDir)
+
+	v := MatchPolicyValidator{}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs:       manifests,
+		Index:      1,
+		IndexGiven: true,
+		Policies:   []string{policyDir},
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Query:	data.main.deny",
+		"Expected to match policies, violations:",
+		"	[1] Service must have label team",
+	}, diff)
+}
+
+func TestMatchPolicyValidatorWhenNoPolicies(t *testing.T) {
+	manifest := makeManifest("kind: Pod")
+
+	v := MatchPolicyValidator{}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs: []common.K8sManifest{manifest},
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Error:",
+		"	no policies defined in test suite or with --policies",
+	}, diff)
+}
@@ -0,0 +1,409 @@
+package validators
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	podSecurityBaseline   = "baseline"
+	podSecurityRestricted = "restricted"
+)
+
+// podSecurityControl a control of Pod Security Standards, checked in restricted level only if restricted
+type podSecurityControl struct {
+	name       string
+	restricted bool
+	check      func(pod *podTemplate) []ruleViolation
+}
+
+// the controls of https://kubernetes.io/docs/concepts/security/pod-security-standards/
+var podSecurityControls = []podSecurityControl{
+	{"HostProcess", false, checkHostProcess},
+	{"Host Namespaces", false, checkHostNamespaces},
+	{"Privileged Containers", false, checkPrivileged},
+	{"Capabilities", false, checkBaselineCapabilities},
+	{"HostPath Volumes", false, checkHostPathVolumes},
+	{"Host Ports", false, checkHostPorts},
+	{"AppArmor", false, checkAppArmor},
+	{"SELinux", false, checkSELinux},
+	{"/proc Mount Type", false, checkProcMount},
+	{"Seccomp", false, checkBaselineSeccomp},
+	{"Sysctls", false, checkSysctls},
+	{"Volume Types", true, checkVolumeTypes},
+	{"Privilege Escalation", true, checkPrivilegeEscalation},
+	{"Running as Non-root", true, checkRunningAsNonRoot},
+	{"Running as Non-root user", true, checkRunningAsNonRootUser},
+	{"Seccomp", true, checkRestrictedSeccomp},
+	{"Capabilities", true, checkRestrictedCapabilities},
+}
+
+var baselineCapabilities = []string{
+	"AUDIT_WRITE", "CHOWN", "DAC_OVERRIDE", "FOWNER", "FSETID", "KILL", "MKNOD",
+	"NET_BIND_SERVICE", "SETFCAP", "SETGID", "SETPCAP", "SETUID", "SYS_CHROOT",
+}
+
+var seLinuxTypes = []string{"", "container_t", "container_init_t", "container_kvm_t", "container_engine_t"}
+
+var safeSysctls = []string{
+	"kernel.shm_rmid_forced",
+	"net.ipv4.ip_local_port_range",
+	"net.ipv4.ip_local_reserved_ports",
+	"net.ipv4.ip_unprivileged_port_start",
+	"net.ipv4.tcp_syncookies",
+	"net.ipv4.ping_group_range",
+	"net.ipv4.tcp_keepalive_time",
+	"net.ipv4.tcp_fin_timeout",
+	"net.ipv4.tcp_keepalive_intvl",
+	"net.ipv4.tcp_keepalive_probes",
+}
+
+var restrictedVolumeTypes = []string{
+	"configMap", "csi", "downwardAPI", "emptyDir", "ephemeral", "persistentVolumeClaim", "projected", "secret",
+}
+
+// securityContext a securityContext of pod or container at path
+type securityContext struct {
+	path    string
+	context map[string]interface{}
+}
+
+// securityContextsOf returns the securityContext of pod and the ones of all containers
+func securityContextsOf(pod *podTemplate) []securityContext {
+	contexts := []securityContext{{
+		pod.pathOf("spec.securityContext"),
+		mapOf(pod.spec["securityContext"]),
+	}}
+	for _, container := range pod.containers() {
+		contexts = append(contexts, securityContext{
+			container.path + ".securityContext",
+			mapOf(container.container["securityContext"]),
+		})
+	}
+	return contexts
+}
+
+func checkHostProcess(pod *podTemplate) []ruleViolation {
+	violations := make([]ruleViolation, 0)
+	for _, ctx := range securityContextsOf(pod) {
+		if mapOf(ctx.context["windowsOptions"])["hostProcess"] == true {
+			violations = append(violations, ruleViolation{ctx.path + ".windowsOptions.hostProcess", "must be false"})
+		}
+	}
+	return violations
+}
+
+func checkHostNamespaces(pod *podTemplate) []ruleViolation {
+	violations := make([]ruleViolation, 0)
+	for _, field := range []string{"hostNetwork", "hostPID", "hostIPC"} {
+		if pod.spec[field] == true {
+			violations = append(violations, ruleViolation{pod.pathOf("spec." + field), "must be false"})
+		}
+	}
+	return violations
+}
+
+func checkPrivileged(pod *podTemplate) []ruleViolation {
+	violations := make([]ruleViolation, 0)
+	for _, ctx := range securityContextsOf(pod)[1:] {
+		if ctx.context["privileged"] == true {
+			violations = append(violations, ruleViolation{ctx.path + ".privileged", "must be false"})
+		}
+	}
+	return violations
+}
+
+func checkBaselineCapabilities(pod *podTemplate) []ruleViolation {
+	violations := make([]ruleViolation, 0)
+	for _, ctx := range securityContextsOf(pod)[1:] {
+		for idx, capability := range listOf(mapOf(ctx.context["capabilities"])["add"]) {
+			if !containsString(baselineCapabilities, stringOf(capability)) {
+				violations = append(violations, ruleViolation{
+					fmt.Sprintf("%s.capabilities.add[%d]", ctx.path, idx),
+					fmt.Sprintf("%v is not allowed", capability),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+func checkHostPathVolumes(pod *podTemplate) []ruleViolation {
+	violations := make([]ruleViolation, 0)
+	for idx, volume := range listOf(pod.spec["volumes"]) {
+		if _, ok := mapOf(volume)["hostPath"]; ok {
+			violations = append(violations, ruleViolation{
+				pod.pathOf(fmt.Sprintf("spec.volumes[%d].hostPath", idx)), "must not be set",
+			})
+		}
+	}
+	return violations
+}
+
+func checkHostPorts(pod *podTemplate) []ruleViolation {
+	violations := make([]ruleViolation, 0)
+	for _, container := range pod.containers() {
+		for idx, port := range listOf(container.container["ports"]) {
+			if hostPort, ok := mapOf(port)["hostPort"]; ok && fmt.Sprint(hostPort) != "0" {
+				violations = append(violations, ruleViolation{
+					fmt.Sprintf("%s.ports[%d].hostPort", container.path, idx), "must not be set",
+				})
+			}
+		}
+	}
+	return violations
+}
+
+func checkAppArmor(pod *podTemplate) []ruleViolation {
+	violations := make([]ruleViolation, 0)
+
+	annotations := mapOf(pod.metadata["annotations"])
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "container.apparmor.security.beta.kubernetes.io/") {
+			continue
+		}
+		profile := stringOf(annotations[key])
+		if profile != "runtime/default" && !strings.HasPrefix(profile, "localhost/") {
+			violations = append(violations, ruleViolation{
+				pod.pathOf(fmt.Sprintf("metadata.annotations[%s]", key)),
+				"must be runtime/default or localhost/*",
+			})
+		}
+	}
+
+	for _, ctx := range securityContextsOf(pod) {
+		if stringOf(mapOf(ctx.context["appArmorProfile"])["type"]) == "Unconfined" {
+			violations = append(violations, ruleViolation{
+				ctx.path + ".appArmorProfile.type", "must be RuntimeDefault or Localhost",
+			})
+		}
+	}
+	return violations
+}
+
+func checkSELinux(pod *podTemplate) []ruleViolation {
+	violations := make([]ruleViolation, 0)
+	for _, ctx := range securityContextsOf(pod) {
+		options := mapOf(ctx.context["seLinuxOptions"])
+		if !containsString(seLinuxTypes, stringOf(options["type"])) {
+			violations = append(violations, ruleViolation{
+				ctx.path + ".seLinuxOptions.type", "must be one of " + strings.Join(seLinuxTypes[1:], ", "),
+			})
+		}
+		for _, field := range []string{"user", "role"} {
+			if _, ok := options[field]; ok {
+				violations = append(violations, ruleViolation{ctx.path + ".seLinuxOptions." + field, "must not be set"})
+			}
+		}
+	}
+	return violations
+}
+
+func checkProcMount(pod *podTemplate) []ruleViolation {
+	violations := make([]ruleViolation, 0)
+	for _, ctx := range securityContextsOf(pod)[1:] {
+		if procMount, ok := ctx.context["procMount"]; ok && procMount != "Default" {
+			violations = append(violations, ruleViolation{ctx.path + ".procMount", "must be Default"})
+		}
+	}
+	return violations
+}
+
+func checkBaselineSeccomp(pod *podTemplate) []ruleViolation {
+	violations := make([]ruleViolation, 0)
+	for _, ctx := range securityContextsOf(pod) {
+		if stringOf(mapOf(ctx.context["seccompProfile"])["type"]) == "Unconfined" {
+			violations = append(violations, ruleViolation{ctx.path + ".seccompProfile.type", "must not be Unconfined"})
+		}
+	}
+	return violations
+}
+
+func checkSysctls(pod *podTemplate) []ruleViolation {
+	violations := make([]ruleViolation, 0)
+	for idx, sysctl := range listOf(mapOf(pod.spec["securityContext"])["sysctls"]) {
+		name := stringOf(mapOf(sysctl)["name"])
+		if !containsString(safeSysctls, name) {
+			violations = append(violations, ruleViolation{
+				pod.pathOf(fmt.Sprintf("spec.securityContext.sysctls[%d].name", idx)),
+				name + " is not allowed",
+			})
+		}
+	}
+	return violations
+}
+
+func checkVolumeTypes(pod *podTemplate) []ruleViolation {
+	violations := make([]ruleViolation, 0)
+	for idx, ele := range listOf(pod.spec["volumes"]) {
+		volume := mapOf(ele)
+		types := make([]string, 0, len(volume))
+		for field := range volume {
+			types = append(types, field)
+		}
+		sort.Strings(types)
+		for _, volumeType := range types {
+			// hostPath volumes are reported in baseline already
+			if volumeType == "name" || volumeType == "hostPath" || containsString(restrictedVolumeTypes, volumeType) {
+				continue
+			}
+			violations = append(violations, ruleViolation{
+				pod.pathOf(fmt.Sprintf("spec.volumes[%d].%s", idx, volumeType)), "must not be set",
+			})
+		}
+	}
+	return violations
+}
+
+func checkPrivilegeEscalation(pod *podTemplate) []ruleViolation {
+	violations := make([]ruleViolation, 0)
+	for _, ctx := range securityContextsOf(pod)[1:] {
+		if ctx.context["allowPrivilegeEscalation"] != false {
+			violations = append(violations, ruleViolation{ctx.path + ".allowPrivilegeEscalation", "must be false"})
+		}
+	}
+	return violations
+}
+
+func checkRunningAsNonRoot(pod *podTemplate) []ruleViolation {
+	contexts := securityContextsOf(pod)
+	podRunAsNonRoot, podSet := contexts[0].context["runAsNonRoot"]
+
+	violations := make([]ruleViolation, 0)
+	if podSet && podRunAsNonRoot != true {
+		violations = append(violations, ruleViolation{contexts[0].path + ".runAsNonRoot", "must be true"})
+	}
+	for _, ctx := range contexts[1:] {
+		runAsNonRoot, set := ctx.context["runAsNonRoot"]
+		if runAsNonRoot == true || (!set && podRunAsNonRoot == true) {
+			continue
+		}
+		violations = append(violations, ruleViolation{
+			ctx.path + ".runAsNonRoot", "must be true, or set in securityContext of pod",
+		})
+	}
+	return violations
+}
+
+func checkRunningAsNonRootUser(pod *podTemplate) []ruleViolation {
+	violations := make([]ruleViolation, 0)
+	for _, ctx := range securityContextsOf(pod) {
+		if runAsUser, ok := ctx.context["runAsUser"]; ok && fmt.Sprint(runAsUser) == "0" {
+			violations = append(violations, ruleViolation{ctx.path + ".runAsUser", "must not be 0"})
+		}
+	}
+	return violations
+}
+
+func checkRestrictedSeccomp(pod *podTemplate) []ruleViolation {
+	contexts := securityContextsOf(pod)
+	seccompTypeOf := func(ctx securityContext) string {
+		return stringOf(mapOf(ctx.context["seccompProfile"])["type"])
+	}
+	allowed := func(seccompType string) bool {
+		return seccompType == "RuntimeDefault" || seccompType == "Localhost"
+	}
+
+	violations := make([]ruleViolation, 0)
+	podType := seccompTypeOf(contexts[0])
+	// Unconfined is reported in baseline already
+	if podType != "" && podType != "Unconfined" && !allowed(podType) {
+		violations = append(violations, ruleViolation{
+			contexts[0].path + ".seccompProfile.type", "must be RuntimeDefault or Localhost",
+		})
+	}
+	for _, ctx := range contexts[1:] {
+		containerType := seccompTypeOf(ctx)
+		if containerType == "Unconfined" || allowed(containerType) || (containerType == "" && allowed(podType)) {
+			continue
+		}
+		violations = append(violations, ruleViolation{
+			ctx.path + ".seccompProfile.type", "must be RuntimeDefault or Localhost, or set in securityContext of pod",
+		})
+	}
+	return violations
+}
+
+func checkRestrictedCapabilities(pod *podTemplate) []ruleViolation {
+	violations := make([]ruleViolation, 0)
+	for _, ctx := range securityContextsOf(pod)[1:] {
+		capabilities := mapOf(ctx.context["capabilities"])
+		dropped := false
+		for _, capability := range listOf(capabilities["drop"]) {
+			dropped = dropped || capability == "ALL"
+		}
+		if !dropped {
+			violations = append(violations, ruleViolation{ctx.path + ".capabilities.drop", "must include ALL"})
+		}
+		for idx, capability := range listOf(capabilities["add"]) {
+			// the ones not allowed in baseline are reported already
+			if capability != "NET_BIND_SERVICE" && containsString(baselineCapabilities, stringOf(capability)) {
+				violations = append(violations, ruleViolation{
+					fmt.Sprintf("%s.capabilities.add[%d]", ctx.path, idx),
+					fmt.Sprintf("%v is not allowed", capability),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// MeetsPodSecurityStandardValidator validate the pods of all workloads rendered from template
+// meet the Pod Security Standards of Level, baseline or restricted
+type MeetsPodSecurityStandardValidator struct {
+	Level string
+}
+
+func (v MeetsPodSecurityStandardValidator) failInfo(violations []string, not bool) []string {
+	if not {
+		return []string{fmt.Sprintf(
+			"Expected NOT to meet the %s Pod Security Standard, but no violations found", v.Level,
+		)}
+	}
+
+	info := []string{fmt.Sprintf("Expected to meet the %s Pod Security Standard, violations:", v.Level)}
+	for _, violation := range violations {
+		info = append(info, "\t"+violation)
+	}
+	return info
+}
+
+// Validate implement Validatable
+func (v MeetsPodSecurityStandardValidator) Validate(context *ValidateContext) (bool, []string) {
+	if v.Level != podSecurityBaseline && v.Level != podSecurityRestricted {
+		return false, splitInfof(errorFormat, fmt.Sprintf(
+			"level should be %s or %s, but got %q", podSecurityBaseline, podSecurityRestricted, v.Level,
+		))
+	}
+
+	violations := make([]string, 0)
+	for idx, manifest := range context.Docs {
+		pod, ok := podTemplateOf(manifest)
+		if !ok {
+			continue
+		}
+		source := manifestOfFile{index: idx, manifest: manifest}
+		for _, control := range podSecurityControls {
+			if control.restricted && v.Level != podSecurityRestricted {
+				continue
+			}
+			for _, violation := range control.check(pod) {
+				violations = append(violations, fmt.Sprintf(
+					"documents[%d] %s/%s [%s] %s: %s",
+					idx, source.kind(), source.name(), control.name, violation.path, violation.message,
+				))
+			}
+		}
+	}
+
+	if (len(violations) == 0) != context.Negative {
+		return true, []string{}
+	}
+	return false, v.failInfo(violations, context.Negative)
+}
@@ -0,0 +1,145 @@
+package validators_test
+
+import (
+	"testing"
+
+	. "github.com/lrills/helm-unittest/unittest/validators"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	"github.com/stretchr/testify/assert"
+)
+
+var restrictedDeployment = makeManifest(`
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      securityContext:
+        runAsNonRoot: true
+        seccompProfile:
+          type: RuntimeDefault
+      containers:
+        - name: web
+          image: nginx:1.17
+          securityContext:
+            allowPrivilegeEscalation: false
+            capabilities:
+              drop: [ALL]
+              add: [NET_BIND_SERVICE]
+      volumes:
+        - name: config
+          configMap:
+            name: config
+`)
+
+var privilegedDaemonSet = makeManifest(`
+kind: DaemonSet
+metadata:
+  name: agent
+spec:
+  template:
+    spec:
+      hostNetwork: true
+      containers:
+        - name: agent
+          image: agent:1.0.0
+          securityContext:
+            privileged: true
+            capabilities:
+              add: [SYS_ADMIN, CHOWN]
+          ports:
+            - containerPort: 9100
+              hostPort: 9100
+      volumes:
+        - name: root
+          hostPath:
+            path: /
+`)
+
+func TestMeetsPodSecurityStandardValidatorWhenOk(t *testing.T) {
+	v := MeetsPodSecurityStandardValidator{Level: "restricted"}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs: []common.K8sManifest{makeManifest("kind: Service"), restrictedDeployment},
+	})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestMeetsPodSecurityStandardValidatorWhenBaselineFail(t *testing.T) {
+	v := MeetsPodSecurityStandardValidator{Level: "baseline"}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs: []common.K8sManifest{restrictedDeployment, privilegedDaemonSet},
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expected to meet the baseline Pod Security Standard, violations:",
+		"	documents[1] DaemonSet/agent [Host Namespaces] spec.template.spec.hostNetwork: must be false",
+		"	documents[1] DaemonSet/agent [Privileged Containers] spec.template.spec.containers[0].securityContext.privileged: must be false",
+		"	documents[1] DaemonSet/agent [Capabilities] spec.template.spec.containers[0].securityContext.capabilities.add[0]: SYS_ADMIN is not allowed",
+		"	documents[1] DaemonSet/agent [HostPath Volumes] spec.template.spec.volumes[0].hostPath: must not be set",
+		"	documents[1] DaemonSet/agent [Host Ports] spec.template.spec.containers[0].ports[0].hostPort: must not be set",
+	}, diff)
+}
+
+func TestMeetsPodSecurityStandardValidatorWhenRestrictedFail(t *testing.T) {
+	v := MeetsPodSecurityStandardValidator{Level: "restricted"}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs: []common.K8sManifest{makeManifest(`
+kind: CronJob
+metadata:
+  name: backup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          securityContext:
+            runAsUser: 0
+          containers:
+            - name: backup
+              image: backup:1.0.0
+              securityContext:
+                capabilities:
+                  add: [CHOWN]
+          volumes:
+            - name: backup
+              nfs:
+                server: nfs.local
+`)},
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expected to meet the restricted Pod Security Standard, violations:",
+		"	documents[0] CronJob/backup [Volume Types] spec.jobTemplate.spec.template.spec.volumes[0].nfs: must not be set",
+		"	documents[0] CronJob/backup [Privilege Escalation] spec.jobTemplate.spec.template.spec.containers[0].securityContext.allowPrivilegeEscalation: must be false",
+		"	documents[0] CronJob/backup [Running as Non-root] spec.jobTemplate.spec.template.spec.containers[0].securityContext.runAsNonRoot: must be true, or set in securityContext of pod",
+		"	documents[0] CronJob/backup [Running as Non-root user] spec.jobTemplate.spec.template.spec.securityContext.runAsUser: must not be 0",
+		"	documents[0] CronJob/backup [Seccomp] spec.jobTemplate.spec.template.spec.containers[0].securityContext.seccompProfile.type: must be RuntimeDefault or Localhost, or set in securityContext of pod",
+		"	documents[0] CronJob/backup [Capabilities] spec.jobTemplate.spec.template.spec.containers[0].securityContext.capabilities.drop: must include ALL",
+		"	documents[0] CronJob/backup [Capabilities] spec.jobTemplate.spec.template.spec.containers[0].securityContext.capabilities.add[0]: CHOWN is not allowed",
+	}, diff)
+}
+
+func TestMeetsPodSecurityStandardValidatorWhenNegativeAndOk(t *testing.T) {
+	v := MeetsPodSecurityStandardValidator{Level: "restricted"}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs:     []common.K8sManifest{privilegedDaemonSet},
+		Negative: true,
+	})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestMeetsPodSecurityStandardValidatorWhenLevelInvalid(t *testing.T) {
+	v := MeetsPodSecurityStandardValidator{Level: "privileged"}
+	pass, diff := v.Validate(&ValidateContext{Docs: []common.K8sManifest{restrictedDeployment}})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{"Error:", "	level should be baseline or restricted, but got \"privileged\""}, diff)
+}
@@ -0,0 +1,143 @@
+package validators
+
+import (
+	"fmt"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+)
+
+// deprecatedAPI an apiVersion of kind deprecated and removed in kubernetes
+type deprecatedAPI struct {
+	apiVersion   string
+	kind         string
+	deprecatedIn [2]int
+	removedIn    [2]int
+	replacement  string
+}
+
+var deprecatedAPIs = []deprecatedAPI{
+	{"extensions/v1beta1", "DaemonSet", [2]int{1, 9}, [2]int{1, 16}, "apps/v1"},
+	{"extensions/v1beta1", "Deployment", [2]int{1, 9}, [2]int{1, 16}, "apps/v1"},
+	{"extensions/v1beta1", "ReplicaSet", [2]int{1, 9}, [2]int{1, 16}, "apps/v1"},
+	{"extensions/v1beta1", "NetworkPolicy", [2]int{1, 9}, [2]int{1, 16}, "networking.k8s.io/v1"},
+	{"extensions/v1beta1", "PodSecurityPolicy", [2]int{1, 10}, [2]int{1, 16}, "policy/v1beta1"},
+	{"extensions/v1beta1", "Ingress", [2]int{1, 14}, [2]int{1, 22}, "networking.k8s.io/v1"},
+	{"apps/v1beta1", "Deployment", [2]int{1, 9}, [2]int{1, 16}, "apps/v1"},
+	{"apps/v1beta1", "StatefulSet", [2]int{1, 9}, [2]int{1, 16}, "apps/v1"},
+	{"apps/v1beta2", "DaemonSet", [2]int{1, 9}, [2]int{1, 16}, "apps/v1"},
+	{"apps/v1beta2", "Deployment", [2]int{1, 9}, [2]int{1, 16}, "apps/v1"},
+	{"apps/v1beta2", "ReplicaSet", [2]int{1, 9}, [2]int{1, 16}, "apps/v1"},
+	{"apps/v1beta2", "StatefulSet", [2]int{1, 9}, [2]int{1, 16}, "apps/v1"},
+	{"networking.k8s.io/v1beta1", "Ingress", [2]int{1, 19}, [2]int{1, 22}, "networking.k8s.io/v1"},
+	{"networking.k8s.io/v1beta1", "IngressClass", [2]int{1, 19}, [2]int{1, 22}, "networking.k8s.io/v1"},
+	{"apiextensions.k8s.io/v1beta1", "CustomResourceDefinition", [2]int{1, 16}, [2]int{1, 22}, "apiextensions.k8s.io/v1"},
+	{"admissionregistration.k8s.io/v1beta1", "MutatingWebhookConfiguration", [2]int{1, 16}, [2]int{1, 22}, "admissionregistration.k8s.io/v1"},
+	{"admissionregistration.k8s.io/v1beta1", "ValidatingWebhookConfiguration", [2]int{1, 16}, [2]int{1, 22}, "admissionregistration.k8s.io/v1"},
+	{"apiregistration.k8s.io/v1beta1", "APIService", [2]int{1, 19}, [2]int{1, 22}, "apiregistration.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1alpha1", "ClusterRole", [2]int{1, 17}, [2]int{1, 22}, "rbac.authorization.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1alpha1", "ClusterRoleBinding", [2]int{1, 17}, [2]int{1, 22}, "rbac.authorization.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1alpha1", "Role", [2]int{1, 17}, [2]int{1, 22}, "rbac.authorization.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1alpha1", "RoleBinding", [2]int{1, 17}, [2]int{1, 22}, "rbac.authorization.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "ClusterRole", [2]int{1, 17}, [2]int{1, 22}, "rbac.authorization.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "ClusterRoleBinding", [2]int{1, 17}, [2]int{1, 22}, "rbac.authorization.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "Role", [2]int{1, 17}, [2]int{1, 22}, "rbac.authorization.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "RoleBinding", [2]int{1, 17}, [2]int{1, 22}, "rbac.authorization.k8s.io/v1"},
+	{"scheduling.k8s.io/v1beta1", "PriorityClass", [2]int{1, 14}, [2]int{1, 22}, "scheduling.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "CSIDriver", [2]int{1, 19}, [2]int{1, 22}, "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "CSINode", [2]int{1, 17}, [2]int{1, 22}, "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "StorageClass", [2]int{1, 19}, [2]int{1, 22}, "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "VolumeAttachment", [2]int{1, 19}, [2]int{1, 22}, "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "CSIStorageCapacity", [2]int{1, 24}, [2]int{1, 27}, "storage.k8s.io/v1"},
+	{"coordination.k8s.io/v1beta1", "Lease", [2]int{1, 19}, [2]int{1, 22}, "coordination.k8s.io/v1"},
+	{"certificates.k8s.io/v1beta1", "CertificateSigningRequest", [2]int{1, 19}, [2]int{1, 22}, "certificates.k8s.io/v1"},
+	{"batch/v1beta1", "CronJob", [2]int{1, 21}, [2]int{1, 25}, "batch/v1"},
+	{"discovery.k8s.io/v1beta1", "EndpointSlice", [2]int{1, 21}, [2]int{1, 25}, "discovery.k8s.io/v1"},
+	{"events.k8s.io/v1beta1", "Event", [2]int{1, 21}, [2]int{1, 25}, "events.k8s.io/v1"},
+	{"policy/v1beta1", "PodDisruptionBudget", [2]int{1, 21}, [2]int{1, 25}, "policy/v1"},
+	{"policy/v1beta1", "PodSecurityPolicy", [2]int{1, 21}, [2]int{1, 25}, ""},
+	{"node.k8s.io/v1beta1", "RuntimeClass", [2]int{1, 20}, [2]int{1, 25}, "node.k8s.io/v1"},
+	{"autoscaling/v2beta1", "HorizontalPodAutoscaler", [2]int{1, 22}, [2]int{1, 25}, "autoscaling/v2"},
+	{"autoscaling/v2beta2", "HorizontalPodAutoscaler", [2]int{1, 23}, [2]int{1, 26}, "autoscaling/v2"},
+	{"flowcontrol.apiserver.k8s.io/v1beta1", "FlowSchema", [2]int{1, 23}, [2]int{1, 26}, "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta1", "PriorityLevelConfiguration", [2]int{1, 23}, [2]int{1, 26}, "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta2", "FlowSchema", [2]int{1, 26}, [2]int{1, 29}, "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta2", "PriorityLevelConfiguration", [2]int{1, 26}, [2]int{1, 29}, "flowcontrol.apiserver.k8s.io/v1"},
+}
+
+// versionReached whether the kube version of major and minor reached version
+func versionReached(major, minor int, version [2]int) bool {
+	return major > version[0] || (major == version[0] && minor >= version[1])
+}
+
+func (api deprecatedAPI) describe(major, minor int) (string, bool) {
+	var status string
+	switch {
+	case versionReached(major, minor, api.removedIn):
+		status = fmt.Sprintf("is removed in %d.%d", api.removedIn[0], api.removedIn[1])
+	case versionReached(major, minor, api.deprecatedIn):
+		status = fmt.Sprintf(
+			"is deprecated in %d.%d and removed in %d.%d",
+			api.deprecatedIn[0], api.deprecatedIn[1], api.removedIn[0], api.removedIn[1],
+		)
+	default:
+		return "", false
+	}
+
+	replacement := "no replacement"
+	if api.replacement != "" {
+		replacement = "use " + api.replacement + " instead"
+	}
+	return fmt.Sprintf("%s %s %s, %s", api.apiVersion, api.kind, status, replacement), true
+}
+
+// NotDeprecatedAPIValidator validate the apiVersion of all manifests rendered form template
+// are not deprecated or removed in KubeVersion, default to the kube version in capabilities
+type NotDeprecatedAPIValidator struct {
+	KubeVersion string
+}
+
+func (v NotDeprecatedAPIValidator) failInfo(kubeVersion string, deprecations []string, not bool) []string {
+	if not {
+		return []string{fmt.Sprintf(
+			"Expected to use APIs deprecated or removed in kubernetes %s, but none found",
+			kubeVersion,
+		)}
+	}
+
+	info := []string{fmt.Sprintf("Expected NOT to use APIs deprecated or removed in kubernetes %s:", kubeVersion)}
+	for _, deprecation := range deprecations {
+		info = append(info, "\t"+deprecation)
+	}
+	return info
+}
+
+// Validate implement Validatable
+func (v NotDeprecatedAPIValidator) Validate(context *ValidateContext) (bool, []string) {
+	kubeVersion := v.KubeVersion
+	if kubeVersion == "" {
+		kubeVersion = context.KubeVersion
+	}
+	major, minor, err := common.ParseKubeVersion(kubeVersion)
+	if err != nil {
+		return false, splitInfof(errorFormat, err.Error())
+	}
+
+	deprecations := make([]string, 0)
+	for idx, manifest := range context.Docs {
+		apiVersion, _ := manifest["apiVersion"].(string)
+		kind, _ := manifest["kind"].(string)
+		for _, api := range deprecatedAPIs {
+			if api.apiVersion != apiVersion || api.kind != kind {
+				continue
+			}
+			if description, deprecated := api.describe(major, minor); deprecated {
+				deprecations = append(deprecations, fmt.Sprintf("documents[%d] %s", idx, description))
+			}
+		}
+	}
+
+	if (len(deprecations) == 0) != context.Negative {
+		return true, []string{}
+	}
+	return false, v.failInfo(kubeVersion, deprecations, context.Negative)
+}
@@ -0,0 +1,70 @@
+package validators_test
+
+import (
+	"testing"
+
+	. "github.com/lrills/helm-unittest/unittest/validators"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	"github.com/stretchr/testify/assert"
+)
+
+var docsToTestNotDeprecatedAPI = []common.K8sManifest{
+	makeManifest("apiVersion: v1\nkind: Service"),
+	makeManifest("apiVersion: extensions/v1beta1\nkind: Deployment"),
+	makeManifest("apiVersion: extensions/v1beta1\nkind: Ingress"),
+}
+
+func TestNotDeprecatedAPIValidatorWhenOk(t *testing.T) {
+	v := NotDeprecatedAPIValidator{}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs:        docsToTestNotDeprecatedAPI,
+		KubeVersion: "1.8",
+	})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestNotDeprecatedAPIValidatorWhenFail(t *testing.T) {
+	v := NotDeprecatedAPIValidator{}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs:        docsToTestNotDeprecatedAPI,
+		KubeVersion: "v1.16.2",
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expected NOT to use APIs deprecated or removed in kubernetes v1.16.2:",
+		"	documents[1] extensions/v1beta1 Deployment is removed in 1.16, use apps/v1 instead",
+		"	documents[2] extensions/v1beta1 Ingress is deprecated in 1.14 and removed in 1.22, use networking.k8s.io/v1 instead",
+	}, diff)
+}
+
+func TestNotDeprecatedAPIValidatorWithKubeVersionSpecified(t *testing.T) {
+	v := NotDeprecatedAPIValidator{KubeVersion: "1.22"}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs:        docsToTestNotDeprecatedAPI[2:],
+		KubeVersion: "1.9",
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expected NOT to use APIs deprecated or removed in kubernetes 1.22:",
+		"	documents[0] extensions/v1beta1 Ingress is removed in 1.22, use networking.k8s.io/v1 instead",
+	}, diff)
+}
+
+func TestNotDeprecatedAPIValidatorWhenNegativeAndFail(t *testing.T) {
+	v := NotDeprecatedAPIValidator{}
+	pass, diff := v.Validate(&ValidateContext{
+		Docs:        docsToTestNotDeprecatedAPI[:1],
+		KubeVersion: "1.16",
+		Negative:    true,
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expected to use APIs deprecated or removed in kubernetes 1.16, but none found",
+	}, diff)
+}
@@ -0,0 +1,207 @@
+package validators
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+)
+
+// builtinReferences the resources always existing in cluster
+var builtinReferences = map[string]bool{
+	"ServiceAccount/default":    true,
+	"ClusterRole/cluster-admin": true,
+	"ClusterRole/admin":         true,
+	"ClusterRole/edit":          true,
+	"ClusterRole/view":          true,
+}
+
+// reference a reference to another resource by kind and name, at path of the manifest
+type reference struct {
+	path string
+	kind string
+	name string
+}
+
+type referenceCollector struct {
+	references []reference
+}
+
+// add the reference if name is not empty and it's not optional
+func (c *referenceCollector) add(path, kind string, name interface{}, optional interface{}) {
+	if stringOf(name) == "" || optional == true {
+		return
+	}
+	c.references = append(c.references, reference{path, kind, stringOf(name)})
+}
+
+func (c *referenceCollector) addPodReferences(template *podTemplate) {
+	spec := template.spec
+	if serviceAccount := stringOf(spec["serviceAccountName"]); serviceAccount != "" {
+		c.add(template.pathOf("spec.serviceAccountName"), "ServiceAccount", serviceAccount, nil)
+	} else {
+		c.add(template.pathOf("spec.serviceAccount"), "ServiceAccount", spec["serviceAccount"], nil)
+	}
+
+	for idx, secret := range listOf(spec["imagePullSecrets"]) {
+		path := template.pathOf(fmt.Sprintf("spec.imagePullSecrets[%d].name", idx))
+		c.add(path, "Secret", mapOf(secret)["name"], nil)
+	}
+
+	for idx, ele := range listOf(spec["volumes"]) {
+		volume := mapOf(ele)
+		volumePath := template.pathOf(fmt.Sprintf("spec.volumes[%d]", idx))
+		configMap := mapOf(volume["configMap"])
+		c.add(volumePath+".configMap.name", "ConfigMap", configMap["name"], configMap["optional"])
+		secret := mapOf(volume["secret"])
+		c.add(volumePath+".secret.secretName", "Secret", secret["secretName"], secret["optional"])
+		claim := mapOf(volume["persistentVolumeClaim"])
+		c.add(volumePath+".persistentVolumeClaim.claimName", "PersistentVolumeClaim", claim["claimName"], nil)
+
+		for sourceIdx, ele := range listOf(mapOf(volume["projected"])["sources"]) {
+			source := mapOf(ele)
+			sourcePath := fmt.Sprintf("%s.projected.sources[%d]", volumePath, sourceIdx)
+			configMap := mapOf(source["configMap"])
+			c.add(sourcePath+".configMap.name", "ConfigMap", configMap["name"], configMap["optional"])
+			secret := mapOf(source["secret"])
+			c.add(sourcePath+".secret.name", "Secret", secret["name"], secret["optional"])
+		}
+	}
+
+	for _, container := range template.containers() {
+		for idx, ele := range listOf(container.container["env"]) {
+			valueFrom := mapOf(mapOf(ele)["valueFrom"])
+			envPath := fmt.Sprintf("%s.env[%d].valueFrom", container.path, idx)
+			configMapRef := mapOf(valueFrom["configMapKeyRef"])
+			c.add(envPath+".configMapKeyRef.name", "ConfigMap", configMapRef["name"], configMapRef["optional"])
+			secretRef := mapOf(valueFrom["secretKeyRef"])
+			c.add(envPath+".secretKeyRef.name", "Secret", secretRef["name"], secretRef["optional"])
+		}
+		for idx, ele := range listOf(container.container["envFrom"]) {
+			envFrom := mapOf(ele)
+			envFromPath := fmt.Sprintf("%s.envFrom[%d]", container.path, idx)
+			configMapRef := mapOf(envFrom["configMapRef"])
+			c.add(envFromPath+".configMapRef.name", "ConfigMap", configMapRef["name"], configMapRef["optional"])
+			secretRef := mapOf(envFrom["secretRef"])
+			c.add(envFromPath+".secretRef.name", "Secret", secretRef["name"], secretRef["optional"])
+		}
+	}
+}
+
+func (c *referenceCollector) addIngressReferences(spec map[string]interface{}) {
+	addBackend := func(path string, backend map[string]interface{}) {
+		// extensions/v1beta1 and networking.k8s.io/v1beta1
+		c.add(path+".serviceName", "Service", backend["serviceName"], nil)
+		// networking.k8s.io/v1
+		c.add(path+".service.name", "Service", mapOf(backend["service"])["name"], nil)
+	}
+
+	addBackend("spec.backend", mapOf(spec["backend"]))
+	addBackend("spec.defaultBackend", mapOf(spec["defaultBackend"]))
+	for ruleIdx, rule := range listOf(spec["rules"]) {
+		for pathIdx, path := range listOf(mapOf(mapOf(rule)["http"])["paths"]) {
+			addBackend(
+				fmt.Sprintf("spec.rules[%d].http.paths[%d].backend", ruleIdx, pathIdx),
+				mapOf(mapOf(path)["backend"]),
+			)
+		}
+	}
+	for idx, tls := range listOf(spec["tls"]) {
+		c.add(fmt.Sprintf("spec.tls[%d].secretName", idx), "Secret", mapOf(tls)["secretName"], nil)
+	}
+}
+
+func (c *referenceCollector) addBindingReferences(binding map[string]interface{}) {
+	roleRef := mapOf(binding["roleRef"])
+	c.add("roleRef.name", stringOf(roleRef["kind"]), roleRef["name"], nil)
+	for idx, ele := range listOf(binding["subjects"]) {
+		subject := mapOf(ele)
+		if stringOf(subject["kind"]) == "ServiceAccount" {
+			c.add(fmt.Sprintf("subjects[%d].name", idx), "ServiceAccount", subject["name"], nil)
+		}
+	}
+}
+
+// referencesOf returns the references to other resources in manifest
+func referencesOf(manifest common.K8sManifest) []reference {
+	collector := referenceCollector{references: make([]reference, 0)}
+	if template, ok := podTemplateOf(manifest); ok {
+		collector.addPodReferences(template)
+	}
+
+	content := mapOf(common.ConvertToJSONCompatible(manifest))
+	spec := mapOf(content["spec"])
+	switch stringOf(content["kind"]) {
+	case "StatefulSet":
+		collector.add("spec.serviceName", "Service", spec["serviceName"], nil)
+	case "Ingress":
+		collector.addIngressReferences(spec)
+	case "RoleBinding", "ClusterRoleBinding":
+		collector.addBindingReferences(content)
+	case "HorizontalPodAutoscaler":
+		scaleTargetRef := mapOf(spec["scaleTargetRef"])
+		collector.add("spec.scaleTargetRef.name", stringOf(scaleTargetRef["kind"]), scaleTargetRef["name"], nil)
+	}
+	return collector.references
+}
+
+// ReferencesExistValidator validate the resources referenced by manifests rendered in the test,
+// like the ConfigMaps mounted or the Service of Ingress backend, are also rendered in the test.
+// The references to the resources of Ignore, in the form of "Kind/name", are not validated
+type ReferencesExistValidator struct {
+	Ignore []string
+}
+
+// ChartWide implement ChartWideValidatable
+func (v ReferencesExistValidator) ChartWide() {}
+
+func (v ReferencesExistValidator) failInfo(danglings []string, not bool) []string {
+	if not {
+		return []string{"Expected some references NOT to exist, but all found"}
+	}
+
+	info := []string{"Expected references to exist, dangling references:"}
+	for _, dangling := range danglings {
+		info = append(info, "\t"+dangling)
+	}
+	return info
+}
+
+// Validate implement Validatable
+func (v ReferencesExistValidator) Validate(context *ValidateContext) (bool, []string) {
+	manifests := flattenManifestsOfFiles(context.AllDocs)
+
+	existing := make(map[string]bool)
+	for key := range builtinReferences {
+		existing[key] = true
+	}
+	for _, ignored := range v.Ignore {
+		existing[ignored] = true
+	}
+	for _, manifest := range manifests {
+		existing[manifest.kind()+"/"+manifest.name()] = true
+	}
+
+	danglings := make([]string, 0)
+	for _, manifest := range manifests {
+		for _, ref := range referencesOf(manifest.manifest) {
+			key := ref.kind + "/" + ref.name
+			if existing[key] || isSystemReference(ref) {
+				continue
+			}
+			danglings = append(danglings, fmt.Sprintf(
+				"%s %s: %s %s not found", manifest.source(), ref.path, ref.kind, ref.name,
+			))
+		}
+	}
+
+	if (len(danglings) == 0) != context.Negative {
+		return true, []string{}
+	}
+	return false, v.failInfo(danglings, context.Negative)
+}
+
+// isSystemReference whether it references the resources of kubernetes system
+func isSystemReference(ref reference) bool {
+	return strings.HasPrefix(ref.name, "system:")
+}
@@ -0,0 +1,127 @@
+package validators_test
+
+import (
+	"testing"
+
+	. "github.com/lrills/helm-unittest/unittest/validators"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	"github.com/stretchr/testify/assert"
+)
+
+var docsToTestReferencesExist = map[string][]common.K8sManifest{
+	"templates/configmap.yaml": {makeManifest(`
+kind: ConfigMap
+metadata:
+  name: config
+`)},
+	"templates/deployment.yaml": {makeManifest(`
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      serviceAccountName: web
+      containers:
+        - name: web
+          envFrom:
+            - configMapRef:
+                name: config
+          env:
+            - name: PASSWORD
+              valueFrom:
+                secretKeyRef:
+                  name: credentials
+                  key: password
+            - name: TOKEN
+              valueFrom:
+                secretKeyRef:
+                  name: token
+                  key: token
+                  optional: true
+      volumes:
+        - name: config
+          configMap:
+            name: config
+        - name: data
+          persistentVolumeClaim:
+            claimName: data
+`)},
+	"templates/ingress.yaml": {makeManifest(`
+kind: Ingress
+metadata:
+  name: web
+spec:
+  rules:
+    - http:
+        paths:
+          - backend:
+              service:
+                name: web
+`)},
+}
+
+func TestReferencesExistValidatorWhenOk(t *testing.T) {
+	docs := map[string][]common.K8sManifest{
+		"templates/configmap.yaml": docsToTestReferencesExist["templates/configmap.yaml"],
+		"templates/deployment.yaml": {makeManifest(`
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      volumes:
+        - name: config
+          configMap:
+            name: config
+`)},
+	}
+
+	v := ReferencesExistValidator{}
+	pass, diff := v.Validate(&ValidateContext{AllDocs: docs})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestReferencesExistValidatorWhenFail(t *testing.T) {
+	v := ReferencesExistValidator{}
+	pass, diff := v.Validate(&ValidateContext{AllDocs: docsToTestReferencesExist})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expected references to exist, dangling references:",
+		"	templates/deployment.yaml documents[0] Deployment/web spec.template.spec.serviceAccountName: ServiceAccount web not found",
+		"	templates/deployment.yaml documents[0] Deployment/web spec.template.spec.volumes[1].persistentVolumeClaim.claimName: PersistentVolumeClaim data not found",
+		"	templates/deployment.yaml documents[0] Deployment/web spec.template.spec.containers[0].env[0].valueFrom.secretKeyRef.name: Secret credentials not found",
+		"	templates/ingress.yaml documents[0] Ingress/web spec.rules[0].http.paths[0].backend.service.name: Service web not found",
+	}, diff)
+}
+
+func TestReferencesExistValidatorWithIgnore(t *testing.T) {
+	v := ReferencesExistValidator{Ignore: []string{
+		"ServiceAccount/web",
+		"PersistentVolumeClaim/data",
+		"Secret/credentials",
+		"Service/web",
+	}}
+	pass, diff := v.Validate(&ValidateContext{AllDocs: docsToTestReferencesExist})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestReferencesExistValidatorWhenNegativeAndFail(t *testing.T) {
+	v := ReferencesExistValidator{}
+	pass, diff := v.Validate(&ValidateContext{
+		AllDocs: map[string][]common.K8sManifest{
+			"templates/configmap.yaml": docsToTestReferencesExist["templates/configmap.yaml"],
+		},
+		Negative: true,
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{"Expected some references NOT to exist, but all found"}, diff)
+}
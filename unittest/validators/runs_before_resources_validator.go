@@ -0,0 +1,35 @@
+package validators
+
+import "strings"
+
+// RunsBeforeResourcesValidator validate the manifest is a pre hook of the phase of release,
+// like "pre-upgrade" on upgrade, so helm runs it before installing or upgrading the other resources
+type RunsBeforeResourcesValidator struct{}
+
+// Validate implement Validatable
+func (v RunsBeforeResourcesValidator) Validate(context *ValidateContext) (bool, []string) {
+	manifest, err := context.GetManifest()
+	if err != nil {
+		return false, splitInfof(errorFormat, err.Error())
+	}
+
+	phase := releasePhaseOf(context.Release)
+	hook, isHook := HookOf(manifest)
+	if (isHook && hook.HasEvent("pre-"+phase)) != context.Negative {
+		return true, []string{}
+	}
+
+	var notAnnotation string
+	if context.Negative {
+		notAnnotation = " NOT"
+	}
+	actual := "not a hook"
+	if isHook {
+		actual = "hook of events: " + strings.Join(hook.Events, ",")
+	}
+	return false, splitInfof(
+		"Expected"+notAnnotation+" to run before resources on "+phase+", as a hook of:%s\nActual:%s",
+		"pre-"+phase,
+		actual,
+	)
+}
@@ -0,0 +1,46 @@
+package validators_test
+
+import (
+	"testing"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	. "github.com/lrills/helm-unittest/unittest/validators"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunsBeforeResourcesValidatorWhenOk(t *testing.T) {
+	validator := RunsBeforeResourcesValidator{}
+	pass, diff := validator.Validate(&ValidateContext{
+		Docs:    []common.K8sManifest{makeManifest(hookDoc)},
+		Release: map[string]interface{}{"IsUpgrade": true},
+	})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestRunsBeforeResourcesValidatorWhenNegativeAndOk(t *testing.T) {
+	validator := RunsBeforeResourcesValidator{}
+	pass, diff := validator.Validate(&ValidateContext{
+		Docs:     []common.K8sManifest{makeManifest(hookDoc)},
+		Release:  map[string]interface{}{"IsUpgrade": true, "IsRollback": true},
+		Negative: true,
+	})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestRunsBeforeResourcesValidatorWhenFail(t *testing.T) {
+	validator := RunsBeforeResourcesValidator{}
+	pass, diff := validator.Validate(&ValidateContext{
+		Docs:    []common.K8sManifest{makeManifest(hookDoc)},
+		Release: map[string]interface{}{"IsRollback": true},
+	})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expected to run before resources on rollback, as a hook of:	pre-rollback",
+		"Actual:	hook of events: pre-install,pre-upgrade",
+	}, diff)
+}
@@ -0,0 +1,125 @@
+package validators
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+)
+
+// ServiceSelectsPodsValidator validate the selector of services rendered in the test,
+// or only the service of Name if given, selects the pods of some workloads rendered
+type ServiceSelectsPodsValidator struct {
+	Name string
+}
+
+// ChartWide implement ChartWideValidatable
+func (v ServiceSelectsPodsValidator) ChartWide() {}
+
+func (v ServiceSelectsPodsValidator) failInfo(danglings []string, noPods bool, not bool) []string {
+	if not {
+		return []string{"Expected some services to select no pods, but all selected"}
+	}
+
+	info := []string{"Expected services to select pods, dangling selectors:"}
+	for _, dangling := range danglings {
+		info = append(info, "\t"+dangling)
+	}
+	if noPods {
+		// the workloads may be in the templates not selected by the suite
+		info = append(info, "No workloads rendered in the test, add their templates to `templates` of the suite if set")
+	}
+	return info
+}
+
+// Validate implement Validatable
+func (v ServiceSelectsPodsValidator) Validate(context *ValidateContext) (bool, []string) {
+	manifests := flattenManifestsOfFiles(context.AllDocs)
+
+	pods := make([]selectablePod, 0)
+	for _, manifest := range manifests {
+		if template, ok := podTemplateOf(manifest.manifest); ok {
+			pods = append(pods, selectablePodOf(template, manifest.manifest))
+		}
+	}
+
+	found := false
+	danglings := make([]string, 0)
+	for _, manifest := range manifests {
+		if manifest.kind() != "Service" || (v.Name != "" && manifest.name() != v.Name) {
+			continue
+		}
+		found = true
+
+		service := mapOf(common.ConvertToJSONCompatible(manifest.manifest))
+		selector := mapOf(mapOf(service["spec"])["selector"])
+		// services without selector are backed by endpoints managed manually
+		if len(selector) == 0 {
+			continue
+		}
+		namespace := stringOf(mapOf(service["metadata"])["namespace"])
+
+		selected := false
+		for _, pod := range pods {
+			if pod.selectedBy(selector, namespace) {
+				selected = true
+				break
+			}
+		}
+		if !selected {
+			danglings = append(danglings, fmt.Sprintf(
+				"%s spec.selector: %s selects no pods",
+				manifest.source(),
+				formatSelector(selector),
+			))
+		}
+	}
+
+	if v.Name != "" && !found {
+		return false, splitInfof(errorFormat, fmt.Sprintf("Service %s not found", v.Name))
+	}
+
+	if (len(danglings) == 0) != context.Negative {
+		return true, []string{}
+	}
+	return false, v.failInfo(danglings, len(pods) == 0, context.Negative)
+}
+
+// selectablePod the labels and namespace of the pods of a workload
+type selectablePod struct {
+	labels    map[string]interface{}
+	namespace string
+}
+
+func selectablePodOf(template *podTemplate, manifest common.K8sManifest) selectablePod {
+	namespace := stringOf(template.metadata["namespace"])
+	if namespace == "" {
+		namespace = stringOf(mapOf(common.ConvertToJSONCompatible(manifest["metadata"]))["namespace"])
+	}
+	return selectablePod{mapOf(template.metadata["labels"]), namespace}
+}
+
+// selectedBy whether the pod is selected by the selector of service in namespace,
+// namespaces are only compared when both specified
+func (p selectablePod) selectedBy(selector map[string]interface{}, namespace string) bool {
+	if namespace != "" && p.namespace != "" && namespace != p.namespace {
+		return false
+	}
+	for key, value := range selector {
+		label, ok := p.labels[key]
+		if !ok || fmt.Sprint(label) != fmt.Sprint(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func formatSelector(selector map[string]interface{}) string {
+	requirements := make([]string, 0, len(selector))
+	for key, value := range selector {
+		requirements = append(requirements, fmt.Sprintf("%s=%v", key, value))
+	}
+	sort.Strings(requirements)
+	return strings.Join(requirements, ",")
+}
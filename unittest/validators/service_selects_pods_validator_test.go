@@ -0,0 +1,103 @@
+package validators_test
+
+import (
+	"testing"
+
+	. "github.com/lrills/helm-unittest/unittest/validators"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+	"github.com/stretchr/testify/assert"
+)
+
+var docsToTestServiceSelectsPods = map[string][]common.K8sManifest{
+	"templates/deployment.yaml": {makeManifest(`
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    metadata:
+      labels:
+        app: web
+        tier: front
+`)},
+	"templates/service.yaml": {
+		makeManifest(`
+kind: Service
+metadata:
+  name: web
+spec:
+  selector:
+    app: web
+`),
+		makeManifest(`
+kind: Service
+metadata:
+  name: api
+spec:
+  selector:
+    app: api
+    tier: back
+`),
+		makeManifest(`
+kind: Service
+metadata:
+  name: external
+spec:
+  type: ExternalName
+  externalName: example.com
+`),
+	},
+}
+
+func TestServiceSelectsPodsValidatorWhenOk(t *testing.T) {
+	v := ServiceSelectsPodsValidator{Name: "web"}
+	pass, diff := v.Validate(&ValidateContext{AllDocs: docsToTestServiceSelectsPods})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestServiceSelectsPodsValidatorWhenFail(t *testing.T) {
+	v := ServiceSelectsPodsValidator{}
+	pass, diff := v.Validate(&ValidateContext{AllDocs: docsToTestServiceSelectsPods})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expected services to select pods, dangling selectors:",
+		"	templates/service.yaml documents[1] Service/api spec.selector: app=api,tier=back selects no pods",
+	}, diff)
+}
+
+func TestServiceSelectsPodsValidatorWhenNoWorkloads(t *testing.T) {
+	v := ServiceSelectsPodsValidator{Name: "web"}
+	pass, diff := v.Validate(&ValidateContext{AllDocs: map[string][]common.K8sManifest{
+		"templates/service.yaml": docsToTestServiceSelectsPods["templates/service.yaml"],
+	}})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{
+		"Expected services to select pods, dangling selectors:",
+		"	templates/service.yaml documents[0] Service/web spec.selector: app=web selects no pods",
+		"No workloads rendered in the test, add their templates to `templates` of the suite if set",
+	}, diff)
+}
+
+func TestServiceSelectsPodsValidatorWhenNegativeAndOk(t *testing.T) {
+	v := ServiceSelectsPodsValidator{Name: "api"}
+	pass, diff := v.Validate(&ValidateContext{
+		AllDocs:  docsToTestServiceSelectsPods,
+		Negative: true,
+	})
+
+	assert.True(t, pass)
+	assert.Equal(t, []string{}, diff)
+}
+
+func TestServiceSelectsPodsValidatorWhenServiceNotFound(t *testing.T) {
+	v := ServiceSelectsPodsValidator{Name: "db"}
+	pass, diff := v.Validate(&ValidateContext{AllDocs: docsToTestServiceSelectsPods})
+
+	assert.False(t, pass)
+	assert.Equal(t, []string{"Error:", "	Service db not found"}, diff)
+}
@@ -33,7 +33,7 @@ Expected` + notAnnotation + ` to match snapshot ` + strconv.Itoa(int(compared.In
 
 // Validate implement Validatable
 func (v MatchSnapshotValidator) Validate(context *ValidateContext) (bool, []string) {
-	manifest, err := context.getManifest()
+	manifest, err := context.GetManifest()
 	if err != nil {
 		return false, splitInfof(errorFormat, err.Error())
 	}
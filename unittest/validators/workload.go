@@ -0,0 +1,139 @@
+package validators
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lrills/helm-unittest/unittest/common"
+)
+
+// podTemplateKeys the keys to the pod template of pod-bearing kinds, empty for Pod itself
+var podTemplateKeys = map[string][]string{
+	"Pod":                   {},
+	"Deployment":            {"spec", "template"},
+	"StatefulSet":           {"spec", "template"},
+	"DaemonSet":             {"spec", "template"},
+	"ReplicaSet":            {"spec", "template"},
+	"ReplicationController": {"spec", "template"},
+	"Job":                   {"spec", "template"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template"},
+}
+
+// podTemplate the pod template of a pod-bearing manifest
+type podTemplate struct {
+	// the path of pod template in manifest, like "spec.template", empty for Pod
+	path     string
+	metadata map[string]interface{}
+	spec     map[string]interface{}
+}
+
+// podTemplateOf returns the pod template of manifest, false if it's not a pod-bearing kind
+func podTemplateOf(manifest common.K8sManifest) (*podTemplate, bool) {
+	kind, _ := manifest["kind"].(string)
+	keys, ok := podTemplateKeys[kind]
+	if !ok {
+		return nil, false
+	}
+
+	template := mapOf(common.ConvertToJSONCompatible(manifest))
+	for _, key := range keys {
+		template = mapOf(template[key])
+	}
+	return &podTemplate{
+		path:     strings.Join(keys, "."),
+		metadata: mapOf(template["metadata"]),
+		spec:     mapOf(template["spec"]),
+	}, true
+}
+
+// pathOf returns the path of the field in pod template, like "spec.template.spec.volumes"
+func (p *podTemplate) pathOf(field string) string {
+	if p.path == "" {
+		return field
+	}
+	return p.path + "." + field
+}
+
+// podContainer a container or init container in pod spec
+type podContainer struct {
+	path      string
+	container map[string]interface{}
+	init      bool
+}
+
+// containers returns the init containers and containers of the pod
+func (p *podTemplate) containers() []podContainer {
+	containers := make([]podContainer, 0)
+	for _, field := range []string{"initContainers", "containers"} {
+		for idx, container := range listOf(p.spec[field]) {
+			containers = append(containers, podContainer{
+				path:      p.pathOf(fmt.Sprintf("spec.%s[%d]", field, idx)),
+				container: mapOf(container),
+				init:      field == "initContainers",
+			})
+		}
+	}
+	return containers
+}
+
+// manifestOfFile a manifest with the file rendered from and its index
+type manifestOfFile struct {
+	file     string
+	index    int
+	manifest common.K8sManifest
+}
+
+// source describe where the manifest located, like "templates/deployment.yaml documents[0] Deployment/web"
+func (m manifestOfFile) source() string {
+	return fmt.Sprintf("%s documents[%d] %s/%s", m.file, m.index, m.kind(), m.name())
+}
+
+func (m manifestOfFile) kind() string {
+	kind, _ := m.manifest["kind"].(string)
+	return kind
+}
+
+func (m manifestOfFile) name() string {
+	name, _ := mapOf(common.ConvertToJSONCompatible(m.manifest["metadata"]))["name"].(string)
+	return name
+}
+
+// flattenManifestsOfFiles returns all manifests ordered by file and index
+func flattenManifestsOfFiles(manifestsOfFiles map[string][]common.K8sManifest) []manifestOfFile {
+	files := make([]string, 0, len(manifestsOfFiles))
+	for file := range manifestsOfFiles {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	manifests := make([]manifestOfFile, 0)
+	for _, file := range files {
+		for idx, manifest := range manifestsOfFiles[file] {
+			manifests = append(manifests, manifestOfFile{file, idx, manifest})
+		}
+	}
+	return manifests
+}
+
+// mapOf returns v if it's a map, or an empty map
+func mapOf(v interface{}) map[string]interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m
+	}
+	return map[string]interface{}{}
+}
+
+// listOf returns v if it's a list, or an empty list
+func listOf(v interface{}) []interface{} {
+	if l, ok := v.([]interface{}); ok {
+		return l
+	}
+	return []interface{}{}
+}
+
+// stringOf returns v if it's a string, or empty string
+func stringOf(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}